@@ -0,0 +1,151 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
+)
+
+// Monitoring backends selectable with -backend.
+const (
+	BackendHealthchecks = "healthchecks"
+	BackendWebhook      = "webhook"
+	BackendKuma         = "kuma"
+	BackendSentry       = "sentry"
+	BackendCronitor     = "cronitor"
+	BackendPushgateway  = "pushgateway"
+)
+
+// redactHandle returns a version of handle safe to display, like in metrics,
+// traces, and the command's environment. Uptime Kuma's monitor name is its
+// push token, so nothing of it is shown.
+func (af *apiFlags) redactHandle(handle string) string {
+	if af.backend == BackendKuma {
+		return "***"
+	}
+
+	return RedactHandle(handle)
+}
+
+// scrubEnvVars returns the environment variables to remove from the command's
+// environment: MonitoringEnvVars, and the ones the backend reads secrets from.
+func (af *apiFlags) scrubEnvVars() []string {
+	switch af.backend {
+	case BackendKuma:
+		// The monitor name is the push token.
+		return append(slices.Clip(MonitoringEnvVars), "CHECK_SLUG")
+	case BackendSentry:
+		return append(slices.Clip(MonitoringEnvVars), "SENTRY_DSN")
+	}
+
+	return MonitoringEnvVars
+}
+
+// checkName returns the check identifier for backends other than
+// Healthchecks, which don't need a ping key. It's the UUID or the slug, if
+// any.
+func (af *apiFlags) checkName() string {
+	if uuid := FromFlagOrEnv(af.uuid, []string{"CHECK_UUID"}); len(uuid) > 0 {
+		return uuid
+	}
+
+	return FromFlagOrEnv(af.slug, []string{"CHECK_SLUG"})
+}
+
+// Pinger returns the Pinger of the selected backend sending pings with
+// client, or printing them with -dry-run. The pings are tagged in client's
+// request attempts. Exits if the backend cannot be set up.
+func (af *apiFlags) Pinger(client *APIClient) Pinger {
+	return client.TagPings(af.backendPinger(client))
+}
+
+func (af *apiFlags) backendPinger(client *APIClient) Pinger {
+	if af.dryRun {
+		if af.backend != BackendHealthchecks {
+			log.Fatalf("-dry-run is only supported with %s backend", BackendHealthchecks)
+		}
+
+		return &DryRunPinger{Client: client, W: os.Stderr}
+	}
+
+	switch af.backend {
+	case BackendHealthchecks:
+		return client
+
+	case BackendWebhook:
+		requireURL(client, "the URL to post to")
+
+		text := DefaultWebhookTemplate
+		if len(af.webhookTemplate) > 0 {
+			b, err := os.ReadFile(af.webhookTemplate)
+			if err != nil {
+				log.Fatal(err)
+			}
+			text = string(b)
+		}
+
+		tmpl, err := NewWebhookTemplate(text)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		hostname, _ := os.Hostname()
+
+		return &WebhookPinger{URL: client.BaseURL, Template: tmpl, Hostname: hostname, Client: client}
+
+	case BackendKuma:
+		requireURL(client, "the address of Uptime Kuma instance")
+		return &KumaPinger{BaseURL: client.BaseURL, Client: client}
+
+	case BackendSentry:
+		warnSecretFlag("sentry-dsn", af.sentryDSN, "SENTRY_DSN")
+		dsn := FromFlagOrEnv(af.sentryDSN, []string{"SENTRY_DSN"})
+		if len(dsn) == 0 {
+			log.Fatal("sentry backend requires a DSN with '-sentry-dsn' or SENTRY_DSN environment variable")
+		}
+
+		d, err := ParseSentryDSN(dsn)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return &SentryPinger{DSN: d, Client: client}
+
+	case BackendCronitor:
+		baseURL := client.BaseURL
+		if baseURL == DefaultBaseURL {
+			baseURL = DefaultCronitorURL
+		}
+
+		hostname, _ := os.Hostname()
+
+		return &CronitorPinger{BaseURL: baseURL, Hostname: hostname, Client: client}
+
+	case BackendPushgateway:
+		requireURL(client, "the address of Pushgateway")
+
+		var grouping []PromLabel
+		for _, l := range af.groupingLabels {
+			name, value, _ := strings.Cut(l, "=")
+			grouping = append(grouping, PromLabel{Name: name, Value: value})
+		}
+
+		return &PushgatewayPinger{BaseURL: client.BaseURL, Grouping: grouping, Client: client}
+	}
+
+	log.Fatalf("unknown backend %q", af.backend)
+	return nil
+}
+
+// requireURL exits if the backend URL wasn't passed and client has the
+// default Healthchecks address.
+func requireURL(client *APIClient, what string) {
+	if client.BaseURL == DefaultBaseURL {
+		log.Fatalf("backend requires %s with '-api-url' or HC_API_URL environment variable", what)
+	}
+}
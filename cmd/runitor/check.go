@@ -0,0 +1,160 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
+)
+
+// checkMain implements the check subcommand, inspecting and pausing or
+// resuming checks through the Management API.
+func checkMain(args []string) int {
+	fs := flag.NewFlagSet(Name+" check", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), Usage, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	af := addAPIFlags(fs)
+	mf := addManagementFlags(fs)
+	lf := addLogFlags(fs)
+	format := fs.String("format", "text", "Output format (text|json)")
+	var tags []string
+	fs.Func("tag", "Only list checks with tag. Can be repeated", listFlag(&tags))
+
+	fs.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(fs.Output(), "unknown output format %q\n", *format)
+		fs.Usage()
+		return 2
+	}
+
+	action := fs.Arg(0)
+	if fs.NArg() != 1 || !slices.Contains([]string{"status", "pause", "resume", "list"}, action) {
+		fmt.Fprintln(fs.Output(), "expected one of: status, pause, resume, list")
+		fs.Usage()
+		return 2
+	}
+
+	lf.setDefault()
+
+	if af.backend != BackendHealthchecks {
+		log.Fatalf("checks can be managed only with %s backend", BackendHealthchecks)
+	}
+
+	m := mf.Client(af.Client())
+	if m == nil {
+		log.Fatal("must pass a Management API key with '-api-key' or HC_API_KEY environment variable")
+	}
+
+	if action == "list" {
+		// Only an explicit -slug filters, not one set for pinging in the
+		// environment.
+		var slug string
+		if flagPassed(fs, "slug") {
+			slug = FromFlagOrEnv(af.slug, nil)
+		}
+
+		checks, err := m.ListChecks(slug, tags)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		printChecks(os.Stdout, *format, checks)
+		return 0
+	}
+
+	handle, htype := af.Handle()
+	c, err := m.ResolveCheck(handle, htype == UUIDHandle)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case action == "status":
+	case af.dryRun:
+		slog.Info("dry run: not changing the check", "action", action)
+	case action == "pause":
+		c, err = m.PauseCheck(c.CheckUUID())
+	case action == "resume":
+		c, err = m.ResumeCheck(c.CheckUUID())
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	printCheck(os.Stdout, *format, c)
+
+	return 0
+}
+
+func printCheck(w io.Writer, format string, c *Check) {
+	if format == "json" {
+		printJSON(w, c)
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(k, v string) {
+		if len(v) > 0 {
+			fmt.Fprintf(tw, "%s:\t%s\n", k, v)
+		}
+	}
+
+	row("UUID", c.CheckUUID())
+	row("Name", c.Name)
+	row("Slug", c.Slug)
+	row("Tags", c.Tags)
+	row("Status", c.Status)
+	if len(c.Schedule) > 0 {
+		row("Schedule", c.Schedule+" "+c.TZ)
+	} else {
+		row("Period", seconds(c.Timeout))
+	}
+	row("Grace", seconds(c.Grace))
+	row("Pings", fmt.Sprint(c.NPings))
+	row("Last ping", c.LastPing)
+	row("Next ping", c.NextPing)
+	tw.Flush()
+}
+
+func printChecks(w io.Writer, format string, checks []Check) {
+	if format == "json" {
+		printJSON(w, checks)
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tSLUG\tSTATUS\tLAST PING\tNAME")
+	for _, c := range checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.CheckUUID(), c.Slug, c.Status, c.LastPing, c.Name)
+	}
+	tw.Flush()
+}
+
+func printJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func seconds(s int) string {
+	if s == 0 {
+		return ""
+	}
+
+	return (time.Duration(s) * time.Second).String()
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
+)
+
+// ctlMain implements the ctl subcommand, sending a command to the control
+// socket of a runitor instance in periodic mode, addressed by its -name.
+//
+// The attach command copies the output of the run in progress to stdout
+// until the command exits, instead of printing the status.
+func ctlMain(args []string) int {
+	fs := flag.NewFlagSet(Name+" ctl", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), Usage, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	controlDir := fs.String("control-dir", "", "Directory of control sockets (default $XDG_RUNTIME_DIR/runitor or a per-user temporary directory)")
+	format := fs.String("format", "text", "Output format (text|json)")
+
+	fs.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(fs.Output(), "unknown output format %q\n", *format)
+		fs.Usage()
+		return 2
+	}
+
+	if fs.NArg() != 2 || !slices.Contains(ControlCommands, fs.Arg(1)) {
+		fmt.Fprintln(fs.Output(), "expected an instance name and one of:", strings.Join(ControlCommands, ", "))
+		fs.Usage()
+		return 2
+	}
+
+	path, err := ControlSocketPath(cmp.Or(*controlDir, ControlDir()), fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if fs.Arg(1) == ControlAttach {
+		if err := AttachControl(path, os.Stdout); err != nil {
+			log.Fatalf("%s: %v", fs.Arg(0), err)
+		}
+
+		return 0
+	}
+
+	status, err := SendControl(path, fs.Arg(1))
+	if err != nil {
+		log.Fatalf("%s: %v", fs.Arg(0), err)
+	}
+
+	printStatus(os.Stdout, *format, status)
+
+	return 0
+}
+
+func printStatus(w io.Writer, format string, s *InstanceStatus) {
+	if format == "json" {
+		printJSON(w, s)
+		return
+	}
+
+	state := "idle"
+	switch {
+	case s.Running:
+		state = "running"
+	case s.Paused:
+		state = "paused"
+	}
+	if s.StopAfterCurrent {
+		state += ", stopping after current run"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(k, v string) {
+		if len(v) > 0 {
+			fmt.Fprintf(tw, "%s:\t%s\n", k, v)
+		}
+	}
+
+	row("Name", s.Name)
+	row("PID", fmt.Sprint(s.PID))
+	row("State", state)
+	if !s.NextRun.IsZero() {
+		row("Next run", s.NextRun.Format(time.RFC3339))
+	}
+	if r := s.LastRun; r != nil {
+		if r.Skipped {
+			row("Last run", "skipped")
+		} else {
+			row("Last run", r.Started.Format(time.RFC3339))
+			row("Duration", r.Duration().Round(time.Millisecond).String())
+			row("Exit code", fmt.Sprint(r.ExitCode))
+			row("Ping delivered", fmt.Sprint(r.PingDelivered))
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,41 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// MonitoringEnvVars are the environment variables runitor reads its check
+// handle, API location, trigger token, and trace exporter headers from. They
+// are scrubbed from the command's environment by default so secrets like the
+// ping key or the collector's auth token don't leak to it.
+var MonitoringEnvVars = []string{"PING_KEY", "HC_PING_KEY", "CHECK_UUID", "HC_API_URL", "HC_API_KEY", "RUNITOR_TRIGGER_TOKEN", "OTEL_EXPORTER_OTLP_HEADERS"}
+
+// envVarFlag parses a "key=value" flag value and appends it to vars.
+func envVarFlag(vars *[]string) func(string) error {
+	return func(s string) error {
+		k, _, ok := strings.Cut(s, "=")
+		if !ok || len(k) == 0 {
+			return errors.New("not in 'key=value' format")
+		}
+
+		*vars = append(*vars, s)
+
+		return nil
+	}
+}
+
+// listFlag appends comma separated flag values to list.
+func listFlag(list *[]string) func(string) error {
+	return func(s string) error {
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.TrimSpace(v); len(v) > 0 {
+				*list = append(*list, v)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
+)
+
+// fakeServerMain implements the fake-server subcommand. It serves a local
+// stand-in for the pinging API to develop and test against without network
+// access, printing each received ping as it arrives.
+func fakeServerMain(args []string) int {
+	fs := flag.NewFlagSet(Name+" fake-server", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), Usage, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	listen := fs.String("listen", "127.0.0.1:8000", "Address to listen on")
+	pingBodyLimit := fs.Uint("ping-body-limit", DefaultFakePingBodyLimit, "Ping body limit to announce in responses and truncate received bodies to. Zero disables it")
+
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fmt.Fprintln(fs.Output(), "unexpected arguments:", fs.Args())
+		fs.Usage()
+		return 2
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	url := "http://" + ln.Addr().String()
+	log.Printf("Listening on %s. Pass '-api-url %s' or set HC_API_URL to send pings here.", url, url)
+	log.Printf("Received pings are served at %s%s", url, FakePingsPath)
+
+	srv := &FakeServer{PingBodyLimit: *pingBodyLimit, Log: os.Stdout}
+	log.Fatal(http.Serve(ln, srv))
+
+	return 0
+}
@@ -0,0 +1,288 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
+)
+
+// apiFlags are the flags shared by the subcommands sending pings.
+type apiFlags struct {
+	fs *flag.FlagSet
+
+	url                              string
+	retries                          uint
+	timeout                          time.Duration
+	pingKey, slug, uuid              string
+	create, dryRun                   bool
+	debugHTTP, revealReqHeaders      bool
+	runId, runIdName, runIdNamespace string
+	reqHeaders                       map[string]string
+	backend, webhookTemplate         string
+	sentryDSN                        string
+	groupingLabels                   []string
+}
+
+// addAPIFlags registers the flags shared by the subcommands sending pings to
+// fs.
+func addAPIFlags(fs *flag.FlagSet) *apiFlags {
+	af := &apiFlags{fs: fs, reqHeaders: make(map[string]string)}
+
+	fs.StringVar(&af.url, "api-url", DefaultBaseURL, "API URL (env: $HC_API_URL)")
+	fs.UintVar(&af.retries, "api-retries", DefaultRetries, "Number of times an API request will be retried if it fails with a transient error")
+	fs.DurationVar(&af.timeout, "api-timeout", DefaultTimeout, "Client timeout per request")
+	fs.StringVar(&af.pingKey, "ping-key", "", "Ping Key (env: $PING_KEY). Use 'file:' prefix for indirection")
+	fs.StringVar(&af.slug, "slug", "", "Slug of check (env: $CHECK_SLUG). Requires a ping key. Use 'file:' prefix for indirection")
+	fs.BoolVar(&af.create, "create", false, "Create a new check if passed slug is not found in the project")
+	fs.StringVar(&af.uuid, "uuid", "", "UUID of check (env: $CHECK_UUID). Use 'file:' prefix for indirection")
+	fs.StringVar(&af.runId, "run-id", "", "Send UUID as the run id instead of generating one (env: $RUNITOR_RUN_ID)")
+	fs.StringVar(&af.runIdName, "run-id-name", "", "Derive the run id from name as a UUIDv5. Same name always produces the same run id")
+	fs.StringVar(&af.runIdNamespace, "run-id-namespace", "", "Namespace UUID for -run-id-name (default runitor's namespace)")
+	fs.BoolVar(&af.dryRun, "dry-run", false, "Print pings to stderr instead of sending them")
+	fs.BoolVar(&af.debugHTTP, "debug-http", false, "Log the API requests, responses, retries, and connection timing with secrets redacted")
+	fs.BoolVar(&af.revealReqHeaders, "debug-http-reveal-req-headers", false, "Don't redact the values of -req-header headers with -debug-http")
+	fs.StringVar(&af.backend, "backend", BackendHealthchecks, "Monitoring backend to ping (healthchecks|webhook|kuma|sentry|cronitor|pushgateway)")
+	fs.StringVar(&af.sentryDSN, "sentry-dsn", "", "DSN of Sentry project for sentry backend (env: $SENTRY_DSN). Use 'file:' prefix for indirection")
+	fs.StringVar(&af.webhookTemplate, "webhook-template", "", "Go template file rendering the JSON payload of webhook backend (default built-in)")
+
+	fs.Func("grouping-label", "Grouping label of pushgateway backend metrics as \"name=value\" string, in addition to job. Can be repeated", envVarFlag(&af.groupingLabels))
+	fs.Func("req-header", "Additional request header as \"key: value\" string", reqHeaderFlag(af.reqHeaders))
+
+	return af
+}
+
+// reqHeaderFlag parses a "key: value" flag value into headers.
+func reqHeaderFlag(headers map[string]string) func(string) error {
+	return func(s string) error {
+		kv := strings.SplitN(s, ":", 2)
+		if len(kv) != 2 {
+			return errors.New("header not in 'key: value' format")
+		}
+
+		headers[kv[0]] = kv[1]
+
+		return nil
+	}
+}
+
+// Handle resolves the check handle from flags and environment variables.
+// Exits if the handle is incomplete.
+func (af *apiFlags) Handle() (string, handleType) {
+	if af.backend != BackendHealthchecks && af.backend != BackendCronitor {
+		if af.backend == BackendKuma {
+			// The monitor name is the push token.
+			warnSecretFlag("slug", af.slug, "CHECK_SLUG")
+			warnSecretFlag("uuid", af.uuid, "CHECK_UUID")
+		}
+
+		name := af.checkName()
+		if len(name) == 0 && af.backend != BackendWebhook {
+			log.Fatalf("%s backend requires the monitor with '-slug' or CHECK_SLUG environment variable", af.backend)
+		}
+
+		return name, NameHandle
+	}
+
+	warnSecretFlag("ping-key", af.pingKey, "PING_KEY")
+	warnSecretFlag("uuid", af.uuid, "CHECK_UUID")
+
+	ch := &handleParams{
+		uuid:    FromFlagOrEnv(af.uuid, []string{"CHECK_UUID"}),
+		slug:    FromFlagOrEnv(af.slug, []string{"CHECK_SLUG"}),
+		pingKey: FromFlagOrEnv(af.pingKey, []string{"PING_KEY", "HC_PING_KEY"}),
+	}
+
+	handle, htype, err := ch.Handle()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if af.create && htype != KeyAndSlugHandle {
+		log.Fatal("-create flag can be used only when passing a handle with ping key and slug")
+	}
+
+	if af.backend == BackendCronitor && htype != KeyAndSlugHandle {
+		log.Fatal("cronitor backend requires the API key with '-ping-key' and the monitor key with '-slug'")
+	}
+
+	return handle, htype
+}
+
+// runIdPassed reports whether an external run id was passed with flags.
+func (af *apiFlags) runIdPassed() bool {
+	return len(af.runId) > 0 || len(af.runIdName) > 0
+}
+
+// RunId returns the external run id passed with flags or the environment. An
+// empty string means no external run id was passed. Exits if the run id is
+// invalid.
+func (af *apiFlags) RunId() string {
+	rid, err := resolveRunId(FromFlagOrEnv(af.runId, []string{"RUNITOR_RUN_ID"}), af.runIdName, af.runIdNamespace)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return rid
+}
+
+// Client returns an APIClient configured with the flags.
+func (af *apiFlags) Client() *APIClient {
+	// api-url flag vs HC_API_URL env var vs default value.
+	//
+	// The reason we cannot use FromFlagOrEnv() here is because we set a
+	// non-empty string default value for -api-url flag. We need to figure
+	// out if we're explicitly passed a flag or not to decide if we should
+	// read the alternate HC_API_URL environment variable.
+	apiURL := af.url
+	if !flagPassed(af.fs, "api-url") {
+		if v, ok := os.LookupEnv("HC_API_URL"); ok && len(v) > 0 {
+			apiURL = v
+		}
+	}
+
+	c := newAPIClient(apiURL, af.retries, af.timeout, af.reqHeaders)
+	if af.debugHTTP {
+		dt := &DebugTransport{Base: c.Transport, Secrets: af.secrets()}
+		if !af.revealReqHeaders {
+			dt.RedactHeaders = slices.Collect(maps.Keys(af.reqHeaders))
+		}
+		c.Transport = dt
+		c.Logger = slog.Default()
+	}
+
+	return c
+}
+
+// secrets returns the secrets passed with flags or the environment that may
+// appear in request URLs, to redact them from debug logs. Check UUIDs are
+// redacted regardless.
+func (af *apiFlags) secrets() []string {
+	secrets := []string{FromFlagOrEnv(af.pingKey, []string{"PING_KEY", "HC_PING_KEY"})}
+
+	switch af.backend {
+	case BackendKuma:
+		// The monitor name is the push token.
+		secrets = append(secrets, af.checkName())
+	case BackendSentry:
+		if d, err := ParseSentryDSN(FromFlagOrEnv(af.sentryDSN, []string{"SENTRY_DSN"})); err == nil {
+			secrets = append(secrets, d.PublicKey)
+		}
+	}
+
+	return secrets
+}
+
+func newAPIClient(baseURL string, retries uint, timeout time.Duration, reqHeaders map[string]string) *APIClient {
+	return &APIClient{
+		BaseURL: baseURL,
+		Retries: max(0, retries), // has to be >= 0
+		Client: &http.Client{
+			Transport: NewDefaultTransportWithResumption(),
+			Timeout:   timeout,
+		},
+		UserAgent:  fmt.Sprintf("%s/%s (%s-%s; +%s)", Name, releaseVersion(), runtime.GOOS, runtime.GOARCH, Homepage),
+		ReqHeaders: reqHeaders,
+	}
+}
+
+// flagPassed reports whether the flag name was explicitly passed to fs.
+func flagPassed(fs *flag.FlagSet, name string) (passed bool) {
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+
+	return
+}
+
+// logFlags are the flags setting up the logging of runitor's own
+// diagnostics.
+type logFlags struct {
+	format string
+	level  slog.Level
+}
+
+// addLogFlags registers the logging flags to fs.
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	lf := &logFlags{}
+
+	fs.StringVar(&lf.format, "log-format", "text", "Format of runitor's own log messages (text|json)")
+	fs.TextVar(&lf.level, "log-level", slog.LevelInfo, "Minimum `level` of runitor's own log messages (debug|info|warn|error)")
+
+	return lf
+}
+
+// setDefault sets up slog's default logger writing to stderr as configured
+// with the flags. Exits if the format is unknown.
+//
+// Messages still logged with the log package, like the fatal ones, are
+// logged at error level.
+func (lf *logFlags) setDefault() {
+	opts := &slog.HandlerOptions{Level: lf.level}
+
+	var h slog.Handler
+	switch lf.format {
+	case "text":
+		h = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		log.Fatalf("unknown log format %q", lf.format)
+	}
+
+	slog.SetLogLoggerLevel(slog.LevelError)
+	slog.SetDefault(slog.New(h))
+}
+
+// mgmtFlags are the flags shared by the subcommands using the Management API.
+type mgmtFlags struct {
+	fs *flag.FlagSet
+
+	url, apiKey string
+}
+
+// addManagementFlags registers the flags shared by the subcommands using the
+// Management API to fs.
+func addManagementFlags(fs *flag.FlagSet) *mgmtFlags {
+	mf := &mgmtFlags{fs: fs}
+
+	fs.StringVar(&mf.url, "management-url", DefaultManagementURL, "Management API URL (env: $HC_MANAGEMENT_URL)")
+	fs.StringVar(&mf.apiKey, "api-key", "", "Management API key (env: $HC_API_KEY). Use 'file:' prefix for indirection")
+
+	return mf
+}
+
+// Client returns a ManagementClient sending requests through api, or nil if no
+// API key was passed.
+func (mf *mgmtFlags) Client(api *APIClient) *ManagementClient {
+	warnSecretFlag("api-key", mf.apiKey, "HC_API_KEY")
+
+	apiKey := FromFlagOrEnv(mf.apiKey, []string{"HC_API_KEY"})
+	if len(apiKey) == 0 {
+		return nil
+	}
+
+	// Same precedence as -api-url and HC_API_URL.
+	mgmtURL := mf.url
+	if !flagPassed(mf.fs, "management-url") {
+		if v, ok := os.LookupEnv("HC_MANAGEMENT_URL"); ok && len(v) > 0 {
+			mgmtURL = v
+		}
+	}
+
+	return &ManagementClient{BaseURL: mgmtURL, APIKey: apiKey, API: api}
+}
@@ -4,19 +4,24 @@ package main
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
-	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
@@ -24,19 +29,56 @@ import (
 
 // RunConfig sets the behavior of a run.
 type RunConfig struct {
-	Quiet                   bool     // No cmd stdout
-	Silent                  bool     // No cmd stdout or stderr
-	NoStartPing             bool     // Don't send Start ping
-	NoOutputInPing          bool     // Don't send command std{out, err} with Success and Failure pings
-	NoRunId                 bool     // Don't generate and send a run id per run in pings
-	Create                  bool     // Create a new check if slug is not found in the project
-	PingBodyLimitIsExplicit bool     // Explicit limit via flags
-	PingBodyLimit           uint     // Truncate ping body to last N bytes
-	OnSuccess               PingType // Ping type to send when command exits successfully
-	OnNonzeroExit           PingType // Ping type to send when command exits with a nonzero code
-	OnExecFail              PingType // Ping type to send when runitor cannot execute the command
+	Quiet                   bool          // No cmd stdout
+	Silent                  bool          // No cmd stdout or stderr
+	NoStartPing             bool          // Don't send Start ping
+	NoOutputInPing          bool          // Don't send command std{out, err} with Success and Failure pings
+	NoRunId                 bool          // Don't generate and send a run id per run in pings
+	RunId                   string        // If non-empty, send this run id instead of generating one
+	Create                  bool          // Create a new check if slug is not found in the project
+	PingBodyLimitIsExplicit bool          // Explicit limit via flags
+	PingBodyLimit           uint          // Truncate ping body to last N bytes
+	OnSuccess               PingType      // Ping type to send when command exits successfully
+	OnNonzeroExit           PingType      // Ping type to send when command exits with a nonzero code
+	OnExecFail              PingType      // Ping type to send when runitor cannot execute the command
+	LockFile                string        // If non-empty, hold an exclusive lock on this file during the run
+	LockWait                time.Duration // How long to wait for a held lock before skipping the run
+	EnvKeysInPing           bool          // List the names of the command's environment variables in the ping body
+	Exec                    ExecConfig    // Environment to execute the command in
+	Tracer                  *Tracer       // If non-nil, trace the run and export its spans
+	Check                   string        // Check handle safe to display in traces and the command's environment
+	KeepOutput              bool          // Keep the output sent in the ping body in the result
+
+	// If non-nil, called with the broadcaster of the command's output to
+	// attach to when the command starts, and with nil when it exits.
+	OnOutput func(*OutputBroadcaster)
+
+	// Per run values exposed to the command as RUNITOR_* environment variables.
+	Attempt     uint      // 1-based sequence number of the run in this runitor process
+	ScheduledAt time.Time // Time the run was scheduled at
+	Trigger     string    // What triggered the run. One of Trigger* constants
 }
 
+// Values for RunConfig.Trigger.
+const (
+	TriggerStart    = "start"    // First run when runitor starts
+	TriggerSchedule = "schedule" // Periodic run at -every interval
+	TriggerSignal   = "signal"   // Immediate run requested with SIGALRM
+	TriggerControl  = "control"  // Immediate run requested over the control socket
+	TriggerHTTP     = "http"     // Immediate run requested on the trigger endpoint
+)
+
+// ExecConfig sets the environment a command gets executed in.
+type ExecConfig struct {
+	Env   []string // Environment in "key=value" form. If nil, runitor's own environment
+	Dir   string   // Working directory. If empty, runitor's own working directory
+	RunAs *RunAs   // If non-nil, credentials to execute the command with
+}
+
+// ExitLockHeld is the exit code returned when a run is skipped because
+// another process held the lock file. (EX_TEMPFAIL from sysexits.h)
+const ExitLockHeld = 75
+
 // Globals used for building help and identification strings.
 
 // Name is the name of this command.
@@ -71,6 +113,7 @@ type handleType int
 const (
 	UUIDHandle handleType = iota
 	KeyAndSlugHandle
+	NameHandle // Check name of backends other than Healthchecks
 )
 
 // Handle composes the final check handle string to be used in the API URL
@@ -145,102 +188,233 @@ ReadFromFile:
 	return strings.TrimSpace(string(bytes))
 }
 
+// resolveRunId returns the external run id passed as id, or derived from name
+// in namespace ns. Returns an empty string if neither id nor name is passed.
+func resolveRunId(id, name, ns string) (string, error) {
+	switch {
+	case len(id) > 0 && len(name) > 0:
+		return "", errors.New("-run-id and -run-id-name are mutually exclusive")
+	case len(id) > 0:
+		rid, err := ParseUUID(id)
+		if err != nil {
+			return "", fmt.Errorf("run id %q: %w", id, err)
+		}
+		return rid, nil
+	case len(name) > 0:
+		if len(ns) == 0 {
+			// runitor's own namespace.
+			var err error
+			if ns, err = NewUUID5(UUIDNamespaceURL, Homepage); err != nil {
+				return "", err
+			}
+		}
+		rid, err := NewUUID5(ns, name)
+		if err != nil {
+			return "", fmt.Errorf("run id namespace %q: %w", ns, err)
+		}
+		return rid, nil
+	}
+
+	return "", nil
+}
+
+// warnSecretFlag logs a warning if a secret was passed as the value of flag
+// name, where it's visible to other users in the process list.
+func warnSecretFlag(name, val, envvar string) {
+	if len(val) == 0 || strings.HasPrefix(val, "file:") {
+		return
+	}
+
+	slog.Warn("flag value is visible to other users in the process list. "+
+		"Use the environment variable or 'file:' prefix for indirection instead.", "flag", "-"+name, "env", envvar)
+}
+
+// Usage is the synopsis of runitor's subcommands.
+const Usage = `usage: runitor [run] [flags] -- command
+       runitor ping [flags] start|success|fail|log|exit-code N
+       runitor check [flags] status|pause|resume|list
+       runitor ctl [flags] name run-now|pause|resume|status|stop-after-current|attach
+       runitor fake-server [flags]
+
+To run a command named like a subcommand, put it after --: runitor -- ping host
+`
+
+// subcommand returns the entry point of the subcommand name. Entry points get
+// the arguments following the subcommand name and return the exit code.
+func subcommand(name string) func(args []string) int {
+	switch name {
+	case "run":
+		return runMain
+	case "ping":
+		return pingMain
+	case "check":
+		return checkMain
+	case "ctl":
+		return ctlMain
+	case "fake-server":
+		return fakeServerMain
+	}
+
+	return nil
+}
+
 func main() {
-	apiURL := flag.String("api-url", DefaultBaseURL, "API URL (env: $HC_API_URL)")
-	apiRetries := flag.Uint("api-retries", DefaultRetries, "Number of times an API request will be retried if it fails with a transient error")
-	apiTimeout := flag.Duration("api-timeout", DefaultTimeout, "Client timeout per request")
-	pingKey := flag.String("ping-key", "", "Ping Key (env: $PING_KEY). Use 'file:' prefix for indirection")
-	slug := flag.String("slug", "", "Slug of check (env: $CHECK_SLUG). Requires a ping key. Use 'file:' prefix for indirection")
-	create := flag.Bool("create", false, "Create a new check if passed slug is not found in the project")
-	uuid := flag.String("uuid", "", "UUID of check (env: $CHECK_UUID). Use 'file:' prefix for indirection")
-	every := flag.Duration("every", 0, "If non-zero, periodically run command at specified interval")
-	quiet := flag.Bool("quiet", false, "Don't capture command's stdout")
-	silent := flag.Bool("silent", false, "Don't capture command's stdout or stderr")
-	onSuccess := pingTypeFlag("on-success", PingTypeSuccess, "Ping type to send when command exits successfully")
-	onNonzeroExit := pingTypeFlag("on-nonzero-exit", PingTypeExitCode, "Ping type to send when command exits with a nonzero code")
-	onExecFail := pingTypeFlag("on-exec-fail", PingTypeFail, "Ping type to send when runitor cannot execute the command")
-	noStartPing := flag.Bool("no-start-ping", false, "Don't send start ping")
-	noOutputInPing := flag.Bool("no-output-in-ping", false, "Don't send command's output in pings")
-	noRunId := flag.Bool("no-run-id", false, "Don't generate and send a run id per run in pings")
-	pingBodyLimit := flag.Uint("ping-body-limit", 10_000, "If non-zero, truncate the ping body to its last N bytes, including a truncation notice.")
-	version := flag.Bool("version", false, "Show version")
-
-	reqHeaders := make(map[string]string)
-	flag.Func("req-header", "Additional request header as \"key: value\" string", func(s string) error {
-		kv := strings.SplitN(s, ":", 2)
-		if len(kv) != 2 {
-			return errors.New("header not in 'key: value' format")
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if sub := subcommand(args[0]); sub != nil {
+			os.Exit(sub(args[1:]))
 		}
+	}
 
-		reqHeaders[kv[0]] = kv[1]
+	// Flag only invocation without a subcommand runs the command as it
+	// did before subcommands were introduced. Commands named like a
+	// subcommand need to follow "--", which is never a subcommand.
+	os.Exit(runMain(args))
+}
 
-		return nil
-	})
+// runMain implements the run subcommand. It returns only in one-shot mode, or
+// when stopped over the control socket in periodic mode.
+func runMain(args []string) int {
+	fs := flag.NewFlagSet(Name+" run", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), Usage, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
 
-	flag.Parse()
+	af := addAPIFlags(fs)
+	mf := addManagementFlags(fs)
+	cf := addCheckFlags(fs)
+	lf := addLogFlags(fs)
+	every := fs.Duration("every", 0, "If non-zero, periodically run command at specified interval")
+	quiet := fs.Bool("quiet", false, "Don't capture command's stdout")
+	silent := fs.Bool("silent", false, "Don't capture command's stdout or stderr")
+	onSuccess := pingTypeFlag(fs, "on-success", PingTypeSuccess, "Ping type to send when command exits successfully")
+	onNonzeroExit := pingTypeFlag(fs, "on-nonzero-exit", PingTypeExitCode, "Ping type to send when command exits with a nonzero code")
+	onExecFail := pingTypeFlag(fs, "on-exec-fail", PingTypeFail, "Ping type to send when runitor cannot execute the command")
+	noStartPing := fs.Bool("no-start-ping", false, "Don't send start ping")
+	noOutputInPing := fs.Bool("no-output-in-ping", false, "Don't send command's output in pings")
+	noRunId := fs.Bool("no-run-id", false, "Don't generate and send a run id per run in pings")
+	lockFile := fs.String("lock", "", "Hold an exclusive lock on file during each run. Skip the run if another process holds it")
+	lockWait := fs.Duration("lock-wait", 0, "How long to wait for a held lock before skipping the run")
+	pingBodyLimit := fs.Uint("ping-body-limit", 10_000, "If non-zero, truncate the ping body to its last N bytes, including a truncation notice.")
+	runAsUser := fs.String("user", "", "Run the command as user (name or uid), with its groups, HOME and USER")
+	runAsGroup := fs.String("group", "", "Run the command with group (name or gid) as its primary group")
+	noScrubEnv := fs.Bool("no-scrub-env", false, "Pass monitoring variables like $PING_KEY and $CHECK_UUID to the command's environment")
+	chdir := fs.String("chdir", "", "Run the command in directory")
+	clearEnv := fs.Bool("clear-env", false, "Run the command with an empty environment, except variables named with -keep-env")
+	envKeysInPing := fs.Bool("env-keys-in-ping", false, "List the names of the command's environment variables in the ping body")
+	name := fs.String("name", "", "Name of the job in metrics (default command's base name)")
+	textfile := fs.String("textfile", "", "Write run metrics to file for node_exporter's textfile collector after every run")
+	control := fs.Bool("control", false, "Serve a control socket named after -name for 'runitor ctl' in periodic mode")
+	controlDir := fs.String("control-dir", "", "Directory of control sockets (default $XDG_RUNTIME_DIR/runitor or a per-user temporary directory)")
+	triggerListen := fs.String("trigger-listen", "", "Accept authenticated run requests at POST /run on address in periodic mode")
+	triggerToken := fs.String("trigger-token", "", "Bearer token run requests must carry (env: $RUNITOR_TRIGGER_TOKEN)")
+	metricsListen := fs.String("metrics-listen", "", "Serve Prometheus metrics at /metrics on address in periodic mode")
+	otlpEndpoint := fs.String("otlp-endpoint", "", "Export a trace of every run to OTLP/HTTP traces endpoint URL (env: $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT)")
+	version := fs.Bool("version", false, "Show version")
+
+	var envCfg EnvConfig
+	fs.Func("env", "Set environment variable for the command as \"key=value\" string. Can be repeated", envVarFlag(&envCfg.Vars))
+	fs.Func("env-file", "Read environment variables for the command from dotenv file. Can be repeated", listFlag(&envCfg.Files))
+	fs.Func("keep-env", "Comma separated names of variables to keep with -clear-env, or to pass despite scrubbing. Can be repeated", listFlag(&envCfg.Keep))
+
+	fs.Parse(args)
 
 	if *version {
 		fmt.Println(Name, releaseVersion())
-		os.Exit(0)
+		return 0
 	}
 
-	ch := &handleParams{
-		uuid:    FromFlagOrEnv(*uuid, []string{"CHECK_UUID"}),
-		slug:    FromFlagOrEnv(*slug, []string{"CHECK_SLUG"}),
-		pingKey: FromFlagOrEnv(*pingKey, []string{"PING_KEY", "HC_PING_KEY"}),
+	lf.setDefault()
+
+	handle, htype := af.Handle()
+
+	if fs.NArg() < 1 {
+		log.Fatal("missing command")
 	}
 
-	handle, htype, err := ch.Handle()
+	// RUNITOR_RUN_ID in the environment is ignored when it cannot be used,
+	// unlike the flags.
+	var fixedRunId string
+	if af.runIdPassed() || (!*noRunId && *every == 0) {
+		fixedRunId = af.RunId()
+	}
+	if len(fixedRunId) > 0 {
+		switch {
+		case *noRunId:
+			log.Fatal("-no-run-id cannot be used with an external run id")
+		case *every > 0:
+			log.Fatal("-every cannot be used with an external run id. Every run needs a distinct run id")
+		}
+	}
+
+	var err error
+	ecfg := ExecConfig{Dir: *chdir}
+	if len(*runAsUser) > 0 || len(*runAsGroup) > 0 {
+		ecfg.RunAs, err = LookupRunAs(*runAsUser, *runAsGroup)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	envCfg.Clear = *clearEnv
+	if !*noScrubEnv {
+		envCfg.Scrub = af.scrubEnvVars()
+	}
+	// The command gets the run id of its run, if any, instead of runitor's.
+	envCfg.Scrub = append(envCfg.Scrub, "RUNITOR_RUN_ID")
+	ecfg.Env, err = envCfg.Environ(ecfg.RunAs)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if *create && htype != KeyAndSlugHandle {
-		log.Fatal("-create flag can be used only when passing a handle with ping key and slug")
+	cmd := fs.Args()
+	client := af.Client()
+
+	if len(*name) == 0 {
+		*name = filepath.Base(cmd[0])
 	}
 
-	// api-url flag vs HC_API_URL env var vs default value.
-	//
-	// The reason we cannot use FromFlagOrEnv() here is because we set a
-	// non-empty string default value for -api-url flag. We need to figure
-	// out if we're explicitly passed a flag or not to decide if we should
-	// read the alternate HC_API_URL environment variable.
-	urlFromArgs := false
-	flag.Visit(func(f *flag.Flag) {
-		if f.Name == "api-url" {
-			urlFromArgs = true
-		}
-	})
+	spec, err := cf.Spec()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pl := &PingLogger{}
+	addAttemptHook(client, pl.OnAttempt)
+	pinger := pl.Pinger(af.Pinger(client))
 
-	if !urlFromArgs {
-		if v, ok := os.LookupEnv("HC_API_URL"); ok && len(v) > 0 {
-			apiURL = &v
+	m := mf.Client(client)
+	if af.backend != BackendHealthchecks {
+		if !spec.IsEmpty() {
+			log.Fatalf("provisioning the check is only supported with %s backend", BackendHealthchecks)
 		}
+		m = nil
 	}
 
-	pingBodyLimitFromArgs := false
-	flag.Visit(func(f *flag.Flag) {
-		if f.Name == "ping-body-limit" {
-			pingBodyLimitFromArgs = true
+	if af.dryRun {
+		// Dry runs don't change the check either.
+		if !spec.IsEmpty() || (m != nil && *every > 0) {
+			slog.Info("dry run: skipping check provisioning")
 		}
-	})
+		m, spec = nil, &CheckSpec{}
+	}
 
-	if flag.NArg() < 1 {
-		log.Fatal("missing command")
+	if m != nil && *every > 0 {
+		// Keep the check's period in sync with the interval.
+		ScheduleSpec(m, handle, htype == UUIDHandle, *every, af.create, spec)
 	}
 
-	retries := max(0, *apiRetries) // has to be >= 0
+	if !spec.IsEmpty() {
+		if m == nil {
+			log.Fatal("provisioning the check requires a Management API key with '-api-key' or HC_API_KEY environment variable")
+		}
 
-	cmd := flag.Args()
-	client := &APIClient{
-		BaseURL: *apiURL,
-		Retries: retries,
-		Client: &http.Client{
-			Transport: NewDefaultTransportWithResumption(),
-			Timeout:   *apiTimeout,
-		},
-		UserAgent:  fmt.Sprintf("%s/%s (%s-%s; +%s)", Name, releaseVersion(), runtime.GOOS, runtime.GOARCH, Homepage),
-		ReqHeaders: reqHeaders,
+		// Failing to provision the check shouldn't stop the command
+		// from running.
+		if _, err := Provision(m, handle, htype, spec); err != nil {
+			slog.Error("provisioning the check failed", "error", err)
+		}
 	}
 
 	cfg := RunConfig{
@@ -249,54 +423,178 @@ func main() {
 		NoStartPing:             *noStartPing,
 		NoOutputInPing:          *noOutputInPing,
 		NoRunId:                 *noRunId,
-		Create:                  *create,
-		PingBodyLimitIsExplicit: pingBodyLimitFromArgs,
+		RunId:                   fixedRunId,
+		Create:                  af.create,
+		PingBodyLimitIsExplicit: flagPassed(fs, "ping-body-limit"),
 		PingBodyLimit:           *pingBodyLimit,
 		OnSuccess:               *onSuccess,
 		OnNonzeroExit:           *onNonzeroExit,
 		OnExecFail:              *onExecFail,
+		LockFile:                *lockFile,
+		LockWait:                *lockWait,
+		EnvKeysInPing:           *envKeysInPing,
+		Exec:                    ecfg,
+		Tracer:                  af.newTracer(*otlpEndpoint),
+		Check:                   af.redactHandle(handle),
 	}
 
-	// Save this invocation so we don't repeat ourselves.
-	task := func() int {
-		return Run(cmd, cfg, handle, client)
+	if *control && *every == 0 {
+		log.Fatal("-control can be used only in periodic mode with -every")
 	}
 
-	exitCode := task()
+	var token string
+	if len(*triggerListen) > 0 {
+		if *every == 0 {
+			log.Fatal("-trigger-listen can be used only in periodic mode with -every")
+		}
+
+		warnSecretFlag("trigger-token", *triggerToken, "RUNITOR_TRIGGER_TOKEN")
+		if token = FromFlagOrEnv(*triggerToken, []string{"RUNITOR_TRIGGER_TOKEN"}); len(token) == 0 {
+			log.Fatal("-trigger-listen requires a token with '-trigger-token' or RUNITOR_TRIGGER_TOKEN environment variable")
+		}
+	}
+
+	metrics := &Metrics{Labels: []PromLabel{{Name: "check", Value: cfg.Check}, {Name: "job", Value: *name}}}
+	if len(*metricsListen) > 0 {
+		if *every == 0 {
+			log.Fatal("-metrics-listen can be used only in periodic mode with -every")
+		}
+
+		addAttemptHook(client, metrics.OnAttempt)
+		pinger = metrics.Pinger(pinger)
+		serveMetrics(*metricsListen, metrics)
+	}
+
+	if cfg.Tracer != nil {
+		addAttemptHook(client, cfg.Tracer.OnAttempt)
+	}
+
+	// Save this invocation so we don't repeat ourselves.
+	var attempt uint
+	task := func(spec runSpec) RunResult {
+		attempt++
+		rcfg := cfg
+		rcfg.Attempt, rcfg.Trigger, rcfg.ScheduledAt = attempt, spec.trigger, spec.scheduledAt
+		if len(spec.runId) > 0 {
+			rcfg.RunId = spec.runId
+		}
+		rcfg.KeepOutput = spec.keepOutput
+		r := Run(cmd, rcfg, handle, pinger)
+
+		metrics.RecordRun(r)
+		if len(*textfile) > 0 && !r.Skipped {
+			if err := WriteTextfile(*textfile, metrics.Runs.Metrics(metrics.Labels, true)); err != nil {
+				slog.Error("writing textfile failed", "path", *textfile, "error", err)
+			}
+		}
+
+		return r
+	}
 
 	// One-shot mode. Exit with command's exit code.
 	if *every == 0 {
-		os.Exit(exitCode)
+		return task(runSpec{trigger: TriggerStart, scheduledAt: time.Now()}).ExitCode
 	}
 
 	// Task scheduler mode. Run the command periodically at specified interval.
-	ticker := time.NewTicker(*every)
-	runNow := make(chan os.Signal, 1)
-	signal.Notify(runNow, syscall.SIGALRM)
-
-	for {
-		select {
-		case <-ticker.C:
-			task()
-
-		case <-runNow:
-			ticker.Reset(*every)
-			task()
+	s := newScheduler(*name, *every, task)
+	if *control {
+		path, err := ControlSocketPath(cmp.Or(*controlDir, ControlDir()), *name)
+		if err != nil {
+			log.Fatal(err)
 		}
+
+		ln, err := ListenControl(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer ln.Close() // removes the socket file
+
+		cfg.OnOutput = s.setOutput
+		go ServeControl(ln, s.control)
 	}
+
+	if len(*triggerListen) > 0 {
+		srv := serveTrigger(*triggerListen, &TriggerHandler{
+			Token: token,
+			Trigger: func(wait bool) (string, <-chan RunResult, error) {
+				runId := cfg.RunId
+				if len(runId) == 0 && !cfg.NoRunId {
+					var err error
+					if runId, err = NewUUID4(); err != nil {
+						return "", nil, err
+					}
+				}
+
+				done, err := s.trigger(runId, wait)
+				return runId, done, err
+			},
+		})
+
+		// Let the replies to the requests for canceled runs go out.
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(ctx)
+		}()
+	}
+
+	s.run(runSpec{trigger: TriggerStart, scheduledAt: time.Now()})
+	s.loop()
+
+	slog.Info("stopped on request")
+
+	return 0
+}
+
+// serveTrigger serves h on addr in the background until the returned server
+// is shut down. Exits if it cannot listen on addr.
+func serveTrigger(addr string, h *TriggerHandler) *http.Server {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := &http.Server{Handler: h}
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	return srv
+}
+
+// serveMetrics serves m at /metrics on addr in the background. Exits if it
+// cannot listen on addr.
+func serveMetrics(addr string, m *Metrics) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m)
+
+	go func() {
+		log.Fatal(http.Serve(ln, mux))
+	}()
 }
 
 // Run function runs the cmd line, tees its output to terminal & ping body as
 // configured in cfg and pings the monitoring API to signal start, and then
-// success or failure of execution. Returns the exit code from the ran command
-// unless execution has failed, in such case 1 is returned.
-func Run(cmd []string, cfg RunConfig, handle string, p Pinger) int {
+// success or failure of execution. Returns the result of the run with the exit
+// code from the ran command unless execution has failed, in such case 1 is
+// returned.
+func Run(cmd []string, cfg RunConfig, handle string, p Pinger) RunResult {
 	var (
 		params PingParams
 		err    error
 	)
 
-	if !cfg.NoRunId {
+	if len(cfg.RunId) > 0 {
+		params.RunId = cfg.RunId
+	} else if !cfg.NoRunId {
 		params.RunId, err = NewUUID4()
 		if err != nil {
 			panic("XXX")
@@ -307,35 +605,90 @@ func Run(cmd []string, cfg RunConfig, handle string, p Pinger) int {
 		params.Create = true
 	}
 
+	logger := slog.Default()
+	if len(params.RunId) > 0 {
+		logger = logger.With("run_id", params.RunId)
+	}
+
+	// Methods of a nil tracer and its spans are no-ops.
+	span := cfg.Tracer.Start("run", nil)
+	span.SetAttr("runitor.check", cfg.Check)
+	span.SetAttr("runitor.attempt", cfg.Attempt)
+	span.SetAttr("runitor.trigger", cfg.Trigger)
+	if len(params.RunId) > 0 {
+		span.SetAttr("runitor.run_id", params.RunId)
+	}
+	defer func() {
+		span.Finish()
+		if err := cfg.Tracer.Flush(); err != nil {
+			logger.Error("exporting trace failed", "error", err)
+		}
+	}()
+	p = cfg.Tracer.Pinger(p, span)
+
+	if len(cfg.LockFile) > 0 {
+		lock, err := AcquireLock(cfg.LockFile, params.RunId, cfg.LockWait)
+		if err != nil {
+			var lhe *LockHeldError
+			if !errors.As(err, &lhe) {
+				// Treat it like a failure to execute the command.
+				msg := fmt.Sprintf("[%s] %v", Name, err)
+				logger.Error("acquiring the lock failed", "lock", cfg.LockFile, "error", err)
+				now := time.Now()
+				err = Ping(p, cfg.OnExecFail, handle, params, 1, strings.NewReader(msg))
+
+				span.SetError(msg)
+				return RunResult{ExitCode: 1, Started: now, Ended: now, PingDelivered: err == nil}
+			}
+
+			// Report the skipped run with a log ping, so it doesn't
+			// go unnoticed if it happens repeatedly.
+			msg := fmt.Sprintf("[%s] Skipped run: %v", Name, err)
+			logger.Warn("skipped run", "lock", cfg.LockFile, "reason", err)
+			span.SetAttr("runitor.skipped", true)
+			p.PingLog(handle, params, strings.NewReader(msg))
+
+			return RunResult{ExitCode: ExitLockHeld, Skipped: true}
+		}
+
+		defer lock.Release()
+
+		if stale, ok := lock.Stale.Get(); ok {
+			logger.Warn("lock was not released by its previous holder", "lock", cfg.LockFile, "holder", stale.String())
+		}
+	}
+
 	if !cfg.NoStartPing {
+		// Failures are logged by the PingLogger wrapping p.
 		icfg, err := p.PingStart(handle, params)
-		if err != nil {
-			log.Print("Ping(start): ", err)
-		} else if instanceLimit, ok := icfg.PingBodyLimit.Get(); ok {
-			if cfg.PingBodyLimitIsExplicit {
-				// Command line flag `-ping-body-limit` was used and
-				// the service instance returned a `Ping-Body-Limit` header.
-				// Pick the smaller value.
-				cfg.PingBodyLimit = min(cfg.PingBodyLimit, instanceLimit)
-			} else {
-				// Let the instance override the runitor default up to 10MB.
-				//
-				// TODO(bdd):
-				// We impose this limit for now because current
-				// ring buffer implementation tries to eagerly
-				// allocate a zero filled array at this
-				// capacity.
-				cfg.PingBodyLimit = min(instanceLimit, 10_000_000)
+		if err == nil {
+			if instanceLimit, ok := icfg.PingBodyLimit.Get(); ok {
+				if cfg.PingBodyLimitIsExplicit {
+					// Command line flag `-ping-body-limit` was used and
+					// the service instance returned a `Ping-Body-Limit` header.
+					// Pick the smaller value.
+					cfg.PingBodyLimit = min(cfg.PingBodyLimit, instanceLimit)
+				} else {
+					// Let the instance override the runitor default up to 10MB.
+					//
+					// TODO(bdd):
+					// We impose this limit for now because current
+					// ring buffer implementation tries to eagerly
+					// allocate a zero filled array at this
+					// capacity.
+					cfg.PingBodyLimit = min(instanceLimit, 10_000_000)
+				}
 			}
 		}
 	}
 
-	var bw io.Writer
-	if cfg.PingBodyLimit > 0 {
-		bw = NewRingBuffer(int(cfg.PingBodyLimit))
-	} else {
-		bw = new(bytes.Buffer)
+	newBuffer := func() io.Writer {
+		if cfg.PingBodyLimit > 0 {
+			return NewRingBuffer(int(cfg.PingBodyLimit))
+		}
+		return new(bytes.Buffer)
 	}
+	bw := newBuffer()
 
 	var mw io.Writer
 	if cfg.NoOutputInPing {
@@ -344,19 +697,55 @@ func Run(cmd []string, cfg RunConfig, handle string, p Pinger) int {
 		mw = io.MultiWriter(os.Stdout, bw)
 	}
 
+	var bc *OutputBroadcaster
+	if cfg.OnOutput != nil {
+		// Followers get what's captured for the ping body, or a buffer
+		// of the same size of their own if the output isn't sent.
+		if cfg.NoOutputInPing {
+			bc = NewOutputBroadcaster(newBuffer())
+		} else {
+			bc = NewOutputBroadcaster(bw)
+		}
+		mw = io.MultiWriter(os.Stdout, bc)
+		cfg.OnOutput(bc)
+	}
+
 	// WARNING:
 	// cmdStdout and cmdStderr either need to be the same Writer or either
 	// of them nil. With two different writers the order of stdout and
 	// stderr writes cannot be preserved.
+	cw := &countingWriter{w: mw}
 	var cmdStdout, cmdStderr io.Writer
 	if !cfg.Quiet {
-		cmdStdout = mw
+		cmdStdout = cw
 	}
 	if !cfg.Silent {
-		cmdStderr = mw
+		cmdStderr = cw
+	}
+
+	ecfg := cfg.Exec
+	if ecfg.Env == nil {
+		ecfg.Env = os.Environ()
+	}
+	// Clip to not write into the backing array shared between runs.
+	ecfg.Env = append(slices.Clip(ecfg.Env), RunEnv(cfg, params)...)
+
+	execSpan := cfg.Tracer.Start("exec", span)
+	execSpan.SetAttr("process.executable.name", filepath.Base(cmd[0]))
+	if execSpan != nil {
+		// Let the command's own spans nest under the exec span.
+		ecfg.Env = append(ecfg.Env, "TRACEPARENT="+execSpan.Traceparent())
 	}
 
-	exitCode, err := Exec(cmd, cmdStdout, cmdStderr)
+	started := time.Now()
+	exitCode, err := Exec(cmd, ecfg, cmdStdout, cmdStderr)
+	ended := time.Now()
+	if bc != nil {
+		cfg.OnOutput(nil)
+		bc.Close()
+	}
+	params.Duration = ended.Sub(started)
+	success := exitCode == 0 && err == nil
 	var ping PingType
 	switch {
 	case exitCode == 0 && err == nil:
@@ -377,6 +766,23 @@ func Run(cmd []string, cfg RunConfig, handle string, p Pinger) int {
 		exitCode = 1
 	}
 
+	params.ExitCode = Some(exitCode)
+
+	execSpan.SetAttr("process.exit_code", exitCode)
+	if !success {
+		msg := fmt.Sprintf("exit code %d", exitCode)
+		if err != nil {
+			msg = err.Error()
+		}
+		execSpan.SetError(msg)
+		span.SetError(msg)
+	}
+	execSpan.Finish()
+
+	if cfg.EnvKeysInPing {
+		fmt.Fprintf(bw, "\n[%s] Environment variables: %s", Name, strings.Join(EnvKeys(ecfg.Env), ", "))
+	}
+
 	var body io.ReadSeeker
 	switch b := bw.(type) {
 	case *bytes.Buffer:
@@ -397,7 +803,62 @@ func Run(cmd []string, cfg RunConfig, handle string, p Pinger) int {
 		body = bytes.NewReader(bb.Bytes())
 	}
 
-	switch ping {
+	var output []byte
+	if cfg.KeepOutput {
+		output, _ = io.ReadAll(body)
+		body.Seek(0, io.SeekStart)
+	}
+
+	logger.Debug("command exited", "exit_code", exitCode, "duration", params.Duration)
+
+	err = Ping(p, ping, handle, params, exitCode, body)
+	span.SetAttr("runitor.ping_delivered", err == nil)
+
+	return RunResult{
+		ExitCode:      exitCode,
+		Success:       success,
+		Started:       started,
+		Ended:         ended,
+		OutputBytes:   cw.n,
+		PingDelivered: err == nil,
+		Output:        output,
+	}
+}
+
+// countingWriter counts the bytes written through it to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// RunEnv returns the environment variables describing the run to the command.
+func RunEnv(cfg RunConfig, params PingParams) []string {
+	env := []string{
+		"RUNITOR_CHECK=" + cfg.Check,
+		"RUNITOR_ATTEMPT=" + strconv.FormatUint(uint64(cfg.Attempt), 10),
+		"RUNITOR_SCHEDULED_AT=" + cfg.ScheduledAt.UTC().Format(time.RFC3339),
+		"RUNITOR_TRIGGER=" + cfg.Trigger,
+		"RUNITOR_PID=" + strconv.Itoa(os.Getpid()),
+		"RUNITOR_VERSION=" + releaseVersion(),
+	}
+
+	if len(params.RunId) > 0 {
+		env = append(env, "RUNITOR_RUN_ID="+params.RunId)
+	}
+
+	return env
+}
+
+// Ping sends a ping of type pt through p. exitCode is only used for
+// PingTypeExitCode.
+func Ping(p Pinger, pt PingType, handle string, params PingParams, exitCode int, body io.ReadSeeker) (err error) {
+	switch pt {
 	case PingTypeSuccess:
 		_, err = p.PingSuccess(handle, params, body)
 	case PingTypeFail:
@@ -411,18 +872,20 @@ func Run(cmd []string, cfg RunConfig, handle string, p Pinger) int {
 		_, err = p.PingExitCode(handle, params, exitCode, body)
 	}
 
-	if err != nil {
-		log.Printf("Ping(%s): %v\n", ping.String(), err)
-	}
-
-	return exitCode
+	return
 }
 
-// Exec function executes cmd[0] with parameters cmd[1:] and redirects its stdout & stderr to passed
-// writers of corresponding parameter names.
-func Exec(cmd []string, stdout, stderr io.Writer) (exitCode int, err error) {
+// Exec function executes cmd[0] with parameters cmd[1:] in the environment
+// described by ecfg and redirects its stdout & stderr to passed writers of
+// corresponding parameter names.
+func Exec(cmd []string, ecfg ExecConfig, stdout, stderr io.Writer) (exitCode int, err error) {
 	c := exec.Command(cmd[0], cmd[1:]...)
 	c.Stdin, c.Stdout, c.Stderr = os.Stdin, stdout, stderr
+	c.Env, c.Dir = ecfg.Env, ecfg.Dir
+
+	if ecfg.RunAs != nil {
+		ecfg.RunAs.SetCredential(c)
+	}
 
 	err = c.Run()
 	exitCode = c.ProcessState.ExitCode()
@@ -0,0 +1,143 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
+)
+
+// pingMain implements the ping subcommand. It sends a single ping for scripts
+// that cannot be wrapped with runitor, using the same check handle, API client
+// and flags as the run subcommand.
+//
+// With -stdin, stdin is read and sent as the ping body, except for start
+// pings. It's opt-in so a ping inside a loop reading stdin doesn't consume the
+// loop's input or wait on a pipe that never closes.
+func pingMain(args []string) int {
+	fs := flag.NewFlagSet(Name+" ping", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), Usage, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	af := addAPIFlags(fs)
+	lf := addLogFlags(fs)
+	stdin := fs.Bool("stdin", false, "Read stdin and send it as the ping body")
+	pingBodyLimit := fs.Uint("ping-body-limit", 10_000, "If non-zero, truncate the ping body to its last N bytes, including a truncation notice.")
+
+	fs.Parse(args)
+
+	start, pt, exitCode, err := parsePingArgs(fs.Args())
+	if err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return 2
+	}
+
+	lf.setDefault()
+
+	handle, _ := af.Handle()
+	client := af.Client()
+	pl := &PingLogger{}
+	addAttemptHook(client, pl.OnAttempt)
+	pinger := pl.Pinger(af.Pinger(client))
+	params := PingParams{RunId: af.RunId(), Create: af.create}
+
+	if start {
+		// Failures are logged by the PingLogger.
+		if _, err := pinger.PingStart(handle, params); err != nil {
+			return 1
+		}
+
+		return 0
+	}
+
+	var body io.ReadSeeker
+	if *stdin {
+		if body, err = stdinBody(*pingBodyLimit); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := Ping(pinger, pt, handle, params, exitCode, body); err != nil {
+		return 1
+	}
+
+	return 0
+}
+
+// parsePingArgs parses the ping type and the exit code argument that follows
+// the exit-code type.
+func parsePingArgs(args []string) (start bool, pt PingType, exitCode int, err error) {
+	if len(args) == 0 {
+		err = errors.New("missing ping type")
+		return
+	}
+
+	if args[0] == "start" {
+		start = true
+		args = args[1:]
+		goto Rest
+	}
+
+	pt, err = PingTypeString(args[0])
+	if err != nil {
+		err = fmt.Errorf("unknown ping type %q", args[0])
+		return
+	}
+	args = args[1:]
+
+	if pt == PingTypeExitCode {
+		if len(args) == 0 {
+			err = errors.New("missing exit code")
+			return
+		}
+
+		exitCode, err = strconv.Atoi(args[0])
+		if err != nil || exitCode < 0 || exitCode > 255 {
+			err = fmt.Errorf("exit code %q is not in [0, 255]", args[0])
+			return
+		}
+		args = args[1:]
+	}
+
+Rest:
+	if len(args) > 0 {
+		err = fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	return
+}
+
+// stdinBody reads stdin into a ping body, truncated to its last limit bytes
+// if limit is non-zero.
+func stdinBody(limit uint) (io.ReadSeeker, error) {
+	if limit == 0 {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+
+		return bytes.NewReader(b), nil
+	}
+
+	rb := NewRingBuffer(int(limit))
+	if _, err := io.Copy(rb, os.Stdin); err != nil {
+		return nil, err
+	}
+
+	if rb.Wrapped() {
+		fmt.Fprintf(rb, "\n[%s] Output truncated to last %d bytes.", Name, rb.Cap())
+	}
+
+	return rb, nil
+}
@@ -19,13 +19,13 @@ const (
 	PingTypeLog
 )
 
-func pingTypeFlag(name string, dflt PingType, usage string) *PingType {
+func pingTypeFlag(fs *flag.FlagSet, name string, dflt PingType, usage string) *PingType {
 	p := new(PingType)
 	*p = dflt
 
 	opts := fmt.Sprintf("%s (default %s)", pingTypeOpts("|"), dflt)
 	usage = usage + " (" + opts + ")"
-	flag.Func(name, usage, func(s string) (err error) {
+	fs.Func(name, usage, func(s string) (err error) {
 		*p, err = PingTypeString(s)
 		if err != nil {
 			err = fmt.Errorf("recognized options: %s", opts)
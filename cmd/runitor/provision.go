@@ -0,0 +1,89 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"time"
+
+	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
+)
+
+// checkFlags are the flags describing the check to provision through the
+// Management API.
+type checkFlags struct {
+	fs *flag.FlagSet
+
+	name, tags, desc, schedule, tz, channels string
+	timeout, grace                           time.Duration
+}
+
+// addCheckFlags registers the flags describing the check to provision to fs.
+func addCheckFlags(fs *flag.FlagSet) *checkFlags {
+	cf := &checkFlags{fs: fs}
+
+	fs.StringVar(&cf.name, "check-name", "", "Provision the check with name. Requires an API key")
+	fs.StringVar(&cf.tags, "check-tags", "", "Provision the check with space separated tags. Requires an API key")
+	fs.StringVar(&cf.desc, "check-desc", "", "Provision the check with description. Requires an API key")
+	fs.DurationVar(&cf.timeout, "check-timeout", 0, "Provision the check with period. Requires an API key")
+	fs.DurationVar(&cf.grace, "check-grace", 0, "Provision the check with grace time. Requires an API key")
+	fs.StringVar(&cf.schedule, "check-schedule", "", "Provision the check with cron expression schedule. Requires an API key")
+	fs.StringVar(&cf.tz, "check-tz", "", "Provision the check with time zone for -check-schedule. Requires an API key")
+	fs.StringVar(&cf.channels, "check-channels", "", "Provision the check with comma separated integration names or ids, or \"*\" for all. Requires an API key")
+
+	return cf
+}
+
+// Spec returns the attributes of the check passed with flags.
+func (cf *checkFlags) Spec() (*CheckSpec, error) {
+	spec := &CheckSpec{}
+
+	str := func(name string, val string) *string {
+		if flagPassed(cf.fs, name) {
+			return &val
+		}
+		return nil
+	}
+
+	secs := func(name string, val time.Duration) *int {
+		if flagPassed(cf.fs, name) {
+			s := int(val.Seconds())
+			return &s
+		}
+		return nil
+	}
+
+	spec.Name = str("check-name", cf.name)
+	spec.Tags = str("check-tags", cf.tags)
+	spec.Desc = str("check-desc", cf.desc)
+	spec.Schedule = str("check-schedule", cf.schedule)
+	spec.TZ = str("check-tz", cf.tz)
+	spec.Channels = str("check-channels", cf.channels)
+	spec.Timeout = secs("check-timeout", cf.timeout)
+	spec.Grace = secs("check-grace", cf.grace)
+
+	if spec.Timeout != nil && spec.Schedule != nil {
+		return nil, errors.New("-check-timeout and -check-schedule are mutually exclusive")
+	}
+
+	return spec, nil
+}
+
+// Provision creates or updates the check identified by handle with the
+// attributes in spec.
+//
+// A check with a UUID handle must already exist. A check with a ping key and
+// slug handle is created if it doesn't exist in the project.
+func Provision(m *ManagementClient, handle string, htype handleType, spec *CheckSpec) (*Check, error) {
+	if htype == UUIDHandle {
+		return m.UpdateCheck(handle, spec)
+	}
+
+	_, slug, _ := strings.Cut(handle, "/")
+	s := *spec
+	s.Slug, s.Unique = &slug, []string{"slug"}
+
+	return m.CreateCheck(&s)
+}
@@ -0,0 +1,238 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
+)
+
+// runSpec describes a run to the task.
+type runSpec struct {
+	trigger     string
+	scheduledAt time.Time
+	runId       string // If non-empty, run with this run id
+	keepOutput  bool   // Keep the output sent in the ping body in the result
+}
+
+// runRequest is a request to run right away.
+type runRequest struct {
+	runSpec
+	done chan<- RunResult // If non-nil, receives the result
+}
+
+// scheduler runs a task periodically at an interval, and right away on
+// SIGALRM or when requested over the control socket or the trigger endpoint.
+// It keeps the status reported over the control socket.
+type scheduler struct {
+	name  string
+	every time.Duration
+	task  func(runSpec) RunResult
+
+	runNow chan runRequest // from the control socket and the trigger endpoint
+	stop   chan struct{}   // stop-after-current request while idle
+
+	mu               sync.Mutex
+	running, paused  bool
+	stopAfterCurrent bool
+	next             time.Time
+	last             *RunResult
+	output           *OutputBroadcaster // of the run in progress
+}
+
+func newScheduler(name string, every time.Duration, task func(runSpec) RunResult) *scheduler {
+	return &scheduler{
+		name:   name,
+		every:  every,
+		task:   task,
+		runNow: make(chan runRequest, 1),
+		stop:   make(chan struct{}, 1),
+	}
+}
+
+// run runs the task, tracking it in the status.
+func (s *scheduler) run(spec runSpec) RunResult {
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
+	r := s.task(spec)
+
+	s.mu.Lock()
+	s.running, s.last = false, &r
+	s.mu.Unlock()
+
+	return r
+}
+
+// setOutput makes the output of the run in progress available to attach to.
+// It's called with nil after the command exits.
+func (s *scheduler) setOutput(b *OutputBroadcaster) {
+	s.mu.Lock()
+	s.output = b
+	s.mu.Unlock()
+}
+
+// stopping reports whether stop-after-current was requested.
+func (s *scheduler) stopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stopAfterCurrent
+}
+
+func (s *scheduler) setNext(t time.Time) {
+	s.mu.Lock()
+	s.next = t
+	s.mu.Unlock()
+}
+
+// loop runs the task at the interval until stop-after-current is requested.
+// Requested runs that haven't started by then are canceled.
+func (s *scheduler) loop() {
+	ticker := time.NewTicker(s.every)
+	s.setNext(time.Now().Add(s.every))
+
+	alarm := make(chan os.Signal, 1)
+	signal.Notify(alarm, syscall.SIGALRM)
+
+	now := func(req runRequest) {
+		ticker.Reset(s.every)
+		req.scheduledAt = time.Now()
+		s.setNext(req.scheduledAt.Add(s.every))
+
+		r := s.run(req.runSpec)
+		if req.done != nil {
+			req.done <- r
+		}
+	}
+
+	for !s.stopping() {
+		select {
+		case t := <-ticker.C:
+			s.setNext(t.Add(s.every))
+
+			s.mu.Lock()
+			paused := s.paused
+			s.mu.Unlock()
+
+			if !paused {
+				s.run(runSpec{trigger: TriggerSchedule, scheduledAt: t})
+			}
+
+		case <-alarm:
+			now(runRequest{runSpec: runSpec{trigger: TriggerSignal}})
+
+		case req := <-s.runNow:
+			now(req)
+
+		case <-s.stop:
+		}
+	}
+
+	// No runs are requested after stop-after-current.
+	select {
+	case req := <-s.runNow:
+		if req.done != nil {
+			close(req.done)
+		}
+	default:
+	}
+}
+
+// trigger requests a run with run id runId from the trigger endpoint. If
+// wait, the returned channel receives the result with its output. Returns
+// ErrRunPending if a requested run hasn't started yet.
+func (s *scheduler) trigger(runId string, wait bool) (<-chan RunResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopAfterCurrent {
+		return nil, errors.New("stopping after the current run")
+	}
+
+	req := runRequest{runSpec: runSpec{trigger: TriggerHTTP, runId: runId, keepOutput: wait}}
+	var done chan RunResult
+	if wait {
+		done = make(chan RunResult, 1)
+		req.done = done
+	}
+
+	select {
+	case s.runNow <- req:
+	default:
+		return nil, ErrRunPending
+	}
+
+	return done, nil
+}
+
+// control handles a command received over the control socket and returns
+// the status after it.
+func (s *scheduler) control(cmd string) (*ControlReply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stream func(io.Writer) error
+
+	switch cmd {
+	case ControlRunNow:
+		if s.stopAfterCurrent {
+			return nil, errors.New("stopping after the current run")
+		}
+
+		select {
+		case s.runNow <- runRequest{runSpec: runSpec{trigger: TriggerControl}}:
+		default:
+			// A run is already requested.
+		}
+
+	case ControlPause:
+		s.paused = true
+
+	case ControlResume:
+		s.paused = false
+
+	case ControlStatus:
+
+	case ControlAttach:
+		if s.output == nil {
+			return nil, errors.New("no run in progress")
+		}
+		stream = s.output.Follow
+
+	case ControlStopAfterCurrent:
+		s.stopAfterCurrent = true
+		if !s.running {
+			select {
+			case s.stop <- struct{}{}:
+			default:
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown command %q", cmd)
+	}
+
+	status := &InstanceStatus{
+		Name:             s.name,
+		PID:              os.Getpid(),
+		Running:          s.running,
+		Paused:           s.paused,
+		StopAfterCurrent: s.stopAfterCurrent,
+		LastRun:          s.last,
+	}
+	if !s.paused && !s.stopAfterCurrent {
+		status.NextRun = s.next
+	}
+
+	return &ControlReply{Status: status, Stream: stream}, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	. "bdd.fi/x/runitor/internal" //lint:ignore ST1001 internal
+)
+
+// newTracer returns a Tracer exporting spans to the OTLP/HTTP traces endpoint
+// passed with -otlp-endpoint or the standard OpenTelemetry environment
+// variables, or nil if no endpoint is set.
+//
+// Spans are exported with a client of their own, so the export requests
+// aren't traced or measured.
+func (af *apiFlags) newTracer(endpoint string) *Tracer {
+	if len(endpoint) == 0 {
+		if v := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); len(v) > 0 {
+			endpoint = v
+		} else if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); len(v) > 0 {
+			// The signal path is appended to the base endpoint.
+			endpoint = strings.TrimSuffix(v, "/") + "/v1/traces"
+		}
+	}
+
+	if len(endpoint) == 0 {
+		return nil
+	}
+
+	if _, err := url.Parse(endpoint); err != nil {
+		log.Fatal("OTLP endpoint: ", err)
+	}
+
+	header, err := parseOTLPHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		log.Fatal("OTEL_EXPORTER_OTLP_HEADERS: ", err)
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if len(serviceName) == 0 {
+		serviceName = Name
+	}
+
+	return &Tracer{
+		Endpoint:       endpoint,
+		Header:         header,
+		ServiceName:    serviceName,
+		ServiceVersion: releaseVersion(),
+		Client:         newAPIClient(endpoint, af.retries, af.timeout, nil),
+	}
+}
+
+// parseOTLPHeaders parses headers in the "key1=value1,key2=value2" format of
+// OTEL_EXPORTER_OTLP_HEADERS. Values are URL encoded.
+func parseOTLPHeaders(s string) (http.Header, error) {
+	header := http.Header{}
+	for kv := range strings.SplitSeq(s, ",") {
+		if len(strings.TrimSpace(kv)) == 0 {
+			continue
+		}
+
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("header %q not in 'key=value' format", kv)
+		}
+
+		v, err := url.QueryUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, err
+		}
+
+		header.Add(strings.TrimSpace(k), v)
+	}
+
+	return header, nil
+}
+
+// addAttemptHook adds hook to the hooks called by c for every request try.
+func addAttemptHook(c *APIClient, hook func(Attempt)) {
+	prev := c.OnAttempt
+	if prev == nil {
+		c.OnAttempt = hook
+		return
+	}
+
+	c.OnAttempt = func(a Attempt) {
+		prev(a)
+		hook(a)
+	}
+}
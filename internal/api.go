@@ -12,10 +12,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	urlpkg "net/url"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -83,6 +85,11 @@ type Pinger interface {
 type PingParams struct {
 	RunId  string
 	Create bool
+
+	// ExitCode and Duration describe the finished command in the final
+	// ping of a run. Healthchecks API doesn't use them.
+	ExitCode Optional[int]
+	Duration time.Duration
 }
 
 // APIClient holds API endpoint URL, client behavior configuration, and embeds http.Client.
@@ -105,10 +112,34 @@ type APIClient struct {
 	// ReqHeaders is a map of additional headers to be sent with every request.
 	ReqHeaders map[string]string
 
+	// OnAttempt, if not nil, is called after every try of a request,
+	// including the retries.
+	OnAttempt func(Attempt)
+
+	// Logger, if not nil, gets the decisions to retry or give up on a
+	// request logged at info level.
+	Logger *slog.Logger
+
+	pingMu    sync.Mutex // serializes the pings sent through TagPings
+	mu        sync.Mutex
+	pt, runId string // of the ping in flight
+
 	// Embed
 	*http.Client
 }
 
+// Attempt describes a single try of a request made by APIClient.
+type Attempt struct {
+	Method     string
+	URL        string
+	Try        uint   // 1-based number of the try
+	Ping       string // Type of the ping the try was made for. Empty outside of pings sent through TagPings
+	RunId      string // Run id of the ping the try was made for
+	StatusCode int    // Zero if no response was received
+	Err        error
+	Duration   time.Duration
+}
+
 // InstanceConfig holds the instance specific configuration parameters received
 // as HTTP headers to ping requests.
 type InstanceConfig struct {
@@ -132,7 +163,35 @@ func (c *InstanceConfig) FromResponse(resp *http.Response) {
 }
 
 // Post wraps embedded http.Client's Post to implement simple retry logic and
-// custom User-Agent header injection.
+// custom User-Agent header injection. See Request.
+func (c *APIClient) Post(url, contentType string, body io.ReadSeeker) (resp *http.Response, err error) {
+	return c.Request("POST", url, http.Header{"Content-Type": {contentType}}, body)
+}
+
+// TagPings returns a Pinger sending pings with p one at a time, and reporting
+// the type and run id of the ping in flight in the Attempts of the requests c
+// makes for it, so attempt hooks can tell pings apart from other requests.
+func (c *APIClient) TagPings(p Pinger) Pinger {
+	return WrapPinger(p, func(pt string, params PingParams, ping PingFunc) (*InstanceConfig, error) {
+		c.pingMu.Lock()
+		defer c.pingMu.Unlock()
+
+		c.mu.Lock()
+		c.pt, c.runId = pt, params.RunId
+		c.mu.Unlock()
+
+		defer func() {
+			c.mu.Lock()
+			c.pt, c.runId = "", ""
+			c.mu.Unlock()
+		}()
+
+		return ping()
+	})
+}
+
+// Request sends an HTTP request with the method, headers in header, and body
+// to url, with simple retry logic and custom User-Agent header injection.
 //
 // Retries:
 // The implementation is inspired from Curl's. Request timeouts and temporary
@@ -143,8 +202,28 @@ func (c *InstanceConfig) FromResponse(resp *http.Response) {
 //
 // User-Agent:
 // If c.UserAgent is not empty, it overrides http.Client's default header.
-func (c *APIClient) Post(url, contentType string, body io.ReadSeeker) (resp *http.Response, err error) {
-	req, err := http.NewRequest("POST", url, body)
+//
+// Headers:
+// c.ReqHeaders are set first, followed by the ones in header.
+//
+// Only responses with status 200 and 201 are successful.
+func (c *APIClient) Request(method, url string, header http.Header, body io.ReadSeeker) (resp *http.Response, err error) {
+	return c.request(method, url, header, body, func(code int) bool {
+		// 201 is returned for pings creating checks.
+		return code == http.StatusOK || code == http.StatusCreated
+	})
+}
+
+// RequestAny2xx is Request, but responses with any 2XX status are
+// successful, like webhook style endpoints respond with 202 or 204.
+func (c *APIClient) RequestAny2xx(method, url string, header http.Header, body io.ReadSeeker) (resp *http.Response, err error) {
+	return c.request(method, url, header, body, func(code int) bool {
+		return code >= 200 && code <= 299
+	})
+}
+
+func (c *APIClient) request(method, url string, header http.Header, body io.ReadSeeker, success func(code int) bool) (resp *http.Response, err error) {
+	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -172,7 +251,12 @@ func (c *APIClient) Post(url, contentType string, body io.ReadSeeker) (resp *htt
 		}
 	}
 
-	req.Header.Set("Content-Type", contentType)
+	for k, vs := range header {
+		req.Header.Del(k)
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
 
 	if len(c.UserAgent) > 0 {
 		req.Header.Set("User-Agent", c.UserAgent)
@@ -193,15 +277,32 @@ Retry:
 		}
 	}
 Try:
+	if tries > 0 && tries <= c.Retries {
+		c.logRetry("retrying request", "method", method, "attempt", tries+1, "backoff", time.Duration(tries)*backoffStep, "reason", err)
+	}
+
 	// Linear backoff at second granularity
 	time.Sleep(time.Duration(tries) * backoffStep)
 
 	if tries++; tries > 1+c.Retries {
 		err = fmt.Errorf("%w after try %d. last error: %v", ErrMaxTries, tries-1, err)
+		c.logRetry("giving up on request", "method", method, "error", err)
 		return
 	}
 
+	attemptStart := time.Now()
 	resp, err = c.Do(req)
+	if c.OnAttempt != nil {
+		a := Attempt{Method: method, URL: url, Try: tries, Err: err, Duration: time.Since(attemptStart)}
+		c.mu.Lock()
+		a.Ping, a.RunId = c.pt, c.runId
+		c.mu.Unlock()
+		if resp != nil {
+			a.StatusCode = resp.StatusCode
+		}
+		c.OnAttempt(a)
+	}
+
 	if err != nil {
 		// Retry timeout and temporary kind of errors
 		var uerr *urlpkg.Error
@@ -210,25 +311,32 @@ Try:
 		}
 
 		// non-recoverable
+		c.logRetry("not retrying request", "method", method, "error", err)
 		return
 	}
 
 	switch {
-	case resp.StatusCode == http.StatusOK:
-		return
-	case resp.StatusCode == http.StatusCreated:
+	case success(resp.StatusCode):
 		return
 	case retriableResponse(resp.StatusCode):
 		code := resp.StatusCode
 		text := http.StatusText(code)
 		err = fmt.Errorf("%d %s", code, text)
+		resp.Body.Close()
 		goto Retry
 	default:
 		err = fmt.Errorf("%w: %s", ErrNonRetriable, resp.Status)
+		c.logRetry("not retrying request", "method", method, "error", err)
 		return
 	}
 }
 
+func (c *APIClient) logRetry(msg string, args ...any) {
+	if c.Logger != nil {
+		c.Logger.Info(msg, args...)
+	}
+}
+
 // PingStart sends a start ping for the check handle.
 func (c *APIClient) PingStart(handle string, params PingParams) (*InstanceConfig, error) {
 	return c.ping(handle, params, "start", nil)
@@ -259,6 +367,26 @@ func (c *APIClient) PingExitCode(handle string, params PingParams, exitCode int,
 }
 
 func (c *APIClient) ping(handle string, params PingParams, typePath string, body io.ReadSeeker) (*InstanceConfig, error) {
+	u, err := c.pingURL(handle, params, typePath)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Post(u.String(), "text/plain", body)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	icfg := &InstanceConfig{}
+	icfg.FromResponse(resp)
+
+	return icfg, nil
+}
+
+// pingURL composes the URL of a ping of typePath kind for the check handle.
+func (c *APIClient) pingURL(handle string, params PingParams, typePath string) (*url.URL, error) {
 	u, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return nil, err
@@ -281,15 +409,5 @@ func (c *APIClient) ping(handle string, params PingParams, typePath string, body
 		u.RawQuery = q.Encode()
 	}
 
-	resp, err := c.Post(u.String(), "text/plain", body)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-
-	icfg := &InstanceConfig{}
-	icfg.FromResponse(resp)
-
-	return icfg, nil
+	return u, nil
 }
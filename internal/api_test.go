@@ -4,6 +4,7 @@ package internal_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -92,6 +93,33 @@ func TestPost201Response(t *testing.T) {
 	}
 }
 
+// Tests if pings treat other 2XX statuses than 200 and 201 as failures, and
+// RequestAny2xx treats them as successes.
+func TestPost2xxResponses(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	defer ts.Close()
+
+	c := &APIClient{
+		BaseURL: ts.URL,
+		Client:  ts.Client(),
+	}
+
+	if _, err := c.PingSuccess(TestHandle, TestPingParamsNone, nil); !errors.Is(err, ErrNonRetriable) {
+		t.Errorf("expected ErrNonRetriable for status 202, got %v", err)
+	}
+
+	resp, err := c.RequestAny2xx("POST", ts.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("expected RequestAny2xx to succeed with status 202, got %v", err)
+	}
+	resp.Body.Close()
+}
+
 // Tests if request timeout errors and HTTP 5XX responses get retried.
 func TestPostRetries(t *testing.T) {
 	t.Parallel()
@@ -434,3 +462,34 @@ func TestResubmitRingBufferBody(t *testing.T) {
 		t.Fatalf("ping request succeeded, but redirect target was never called")
 	}
 }
+
+// Tests if the Attempts of requests made for pings sent through TagPings
+// carry the type and run id of the ping, and the ones of other requests don't.
+func TestTagPings(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	var attempts []Attempt
+	c := &APIClient{BaseURL: ts.URL, Client: ts.Client(), OnAttempt: func(a Attempt) {
+		attempts = append(attempts, a)
+	}}
+
+	if _, err := c.TagPings(c).PingExitCode(TestHandle, TestPingParamsWithRID, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Request("GET", ts.URL, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(attempts))
+	}
+	if a := attempts[0]; a.Ping != "exit-code" || a.RunId != TestRunId {
+		t.Errorf("expected the ping's type and run id, got %q, %q", a.Ping, a.RunId)
+	}
+	if a := attempts[1]; a.Ping != "" || a.RunId != "" {
+		t.Errorf("expected a request outside of pings to be untagged, got %q, %q", a.Ping, a.RunId)
+	}
+}
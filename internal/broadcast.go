@@ -0,0 +1,131 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+// followerBacklog is the number of writes buffered for a follower before it's
+// dropped for falling behind.
+const followerBacklog = 256
+
+// ErrFollowerLagged is returned by Follow if the follower fell behind the
+// output and was dropped.
+var ErrFollowerLagged = errors.New("fell behind the output")
+
+// OutputBroadcaster is an io.Writer passing the output of a run to the ping
+// body buffer, and copying it to followers attached while the run is in
+// progress. The ping body is the same as if it were written to directly.
+type OutputBroadcaster struct {
+	mu        sync.Mutex
+	w         io.Writer
+	followers map[chan []byte]struct{}
+	lagged    map[chan []byte]bool
+	closed    bool
+}
+
+// NewOutputBroadcaster returns an OutputBroadcaster writing to the ping body
+// buffer w.
+func NewOutputBroadcaster(w io.Writer) *OutputBroadcaster {
+	return &OutputBroadcaster{
+		w:         w,
+		followers: make(map[chan []byte]struct{}),
+		lagged:    make(map[chan []byte]bool),
+	}
+}
+
+// Write writes p to the ping body buffer and the followers. Followers that
+// fell behind are dropped instead of blocking the write.
+func (b *OutputBroadcaster) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n, err := b.w.Write(p)
+
+	for ch := range b.followers {
+		select {
+		case ch <- bytes.Clone(p):
+		default:
+			b.lagged[ch] = true
+			b.drop(ch)
+		}
+	}
+
+	return n, err
+}
+
+func (b *OutputBroadcaster) drop(ch chan []byte) {
+	delete(b.followers, ch)
+	close(ch)
+}
+
+// snapshot returns the output captured in the ping body buffer so far.
+func (b *OutputBroadcaster) snapshot() []byte {
+	switch w := b.w.(type) {
+	case *RingBuffer:
+		return w.Snapshot()
+	case *bytes.Buffer:
+		return bytes.Clone(w.Bytes())
+	}
+
+	return nil
+}
+
+// Follow writes the output captured so far to w and then follows the new
+// output as it's written, until Close is called or writing to w fails.
+func (b *OutputBroadcaster) Follow(w io.Writer) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+
+	captured := b.snapshot()
+	ch := make(chan []byte, followerBacklog)
+	b.followers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	err := func() error {
+		if _, err := w.Write(captured); err != nil {
+			return err
+		}
+
+		for p := range ch {
+			if _, err := w.Write(p); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.followers[ch]; ok {
+		b.drop(ch)
+	}
+	if err == nil && b.lagged[ch] {
+		err = ErrFollowerLagged
+	}
+	delete(b.lagged, ch)
+
+	return err
+}
+
+// Close ends following the output. It's called when the command exits.
+func (b *OutputBroadcaster) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.followers {
+		b.drop(ch)
+	}
+
+	return nil
+}
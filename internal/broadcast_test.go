@@ -0,0 +1,99 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if a follower gets the output captured in the ring buffer so far and
+// then the new output, without changing the ping body.
+func TestOutputBroadcaster(t *testing.T) {
+	t.Parallel()
+
+	rb := NewRingBuffer(8)
+	b := NewOutputBroadcaster(rb)
+	fmt.Fprint(b, "0123456789")
+
+	pr, pw := io.Pipe()
+	done := make(chan error)
+	go func() {
+		err := b.Follow(pw)
+		pw.Close()
+		done <- err
+	}()
+
+	// The captured output is written first.
+	p := make([]byte, 8)
+	if _, err := io.ReadFull(pr, p); err != nil || string(p) != "23456789" {
+		t.Fatalf("expected captured output, got %q, %v", p, err)
+	}
+
+	fmt.Fprint(b, "ab")
+	p = p[:2]
+	if _, err := io.ReadFull(pr, p); err != nil || string(p) != "ab" {
+		t.Fatalf("expected new output, got %q, %v", p, err)
+	}
+
+	b.Close()
+	if rest, _ := io.ReadAll(pr); len(rest) > 0 {
+		t.Errorf("unexpected output after close: %q", rest)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Follow failed: %v", err)
+	}
+
+	body, _ := io.ReadAll(rb)
+	if string(body) != "456789ab" {
+		t.Errorf("expected ping body to be unchanged, got %q", body)
+	}
+
+	// Following after close returns right away.
+	if err := b.Follow(io.Discard); err != nil {
+		t.Errorf("Follow after close failed: %v", err)
+	}
+}
+
+// gateWriter blocks writes until release is closed, signaling started on
+// the first one.
+type gateWriter struct {
+	once             sync.Once
+	started, release chan struct{}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	return len(p), nil
+}
+
+// Tests if a follower that falls behind is dropped instead of blocking the
+// writes.
+func TestOutputBroadcasterLagged(t *testing.T) {
+	t.Parallel()
+
+	b := NewOutputBroadcaster(io.Discard)
+
+	w := &gateWriter{started: make(chan struct{}), release: make(chan struct{})}
+	done := make(chan error)
+	go func() {
+		done <- b.Follow(w)
+	}()
+
+	// The follower is stuck writing the captured output.
+	<-w.started
+	for range 1000 {
+		fmt.Fprint(b, "x")
+	}
+	close(w.release)
+
+	if err := <-done; !errors.Is(err, ErrFollowerLagged) {
+		t.Errorf("expected ErrFollowerLagged, got %v", err)
+	}
+}
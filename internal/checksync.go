@@ -0,0 +1,88 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Healthchecks' limits for check period and grace time.
+const (
+	minCheckPeriod = time.Minute
+	maxCheckPeriod = 365 * 24 * time.Hour
+)
+
+// ScheduleFor returns the period and grace time, in seconds, of a check pinged
+// at every interval. Grace time is a tenth of the interval, to allow for
+// variation in run duration, but at least a minute.
+func ScheduleFor(every time.Duration) (timeout, grace int) {
+	clamp := func(d time.Duration) int {
+		return int(min(max(d, minCheckPeriod), maxCheckPeriod).Seconds())
+	}
+
+	return clamp(every), clamp(every / 10)
+}
+
+// ResolveCheck fetches the check identified by the ping handle, by its UUID
+// if isUUID, or by the slug after the ping key in the API key's project
+// otherwise.
+func (m *ManagementClient) ResolveCheck(handle string, isUUID bool) (*Check, error) {
+	if isUUID {
+		return m.GetCheck(handle)
+	}
+
+	_, slug, _ := strings.Cut(handle, "/")
+	checks, err := m.ListChecks(slug, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(checks) {
+	case 0:
+		return nil, ErrCheckNotFound
+	case 1:
+		return &checks[0], nil
+	default:
+		return nil, fmt.Errorf("%d checks with slug %s", len(checks), slug)
+	}
+}
+
+// ScheduleSpec adds the period and grace time matching every interval to spec
+// for the check identified by handle, unless spec already specifies a period
+// or schedule. A grace time in spec is kept.
+//
+// Only an existing check with a simple period is synced. A check with a cron
+// schedule is left alone with a warning. If the check cannot be fetched, spec
+// is left as is, unless create allows a check with a ping key and slug handle
+// to be created. If spec has no other attributes, it's also left empty when
+// the check's period and grace time already match, so the check doesn't get
+// updated on every start.
+func ScheduleSpec(m *ManagementClient, handle string, isUUID bool, every time.Duration, create bool, spec *CheckSpec) {
+	if spec.Timeout != nil || spec.Schedule != nil {
+		return
+	}
+
+	timeout, grace := ScheduleFor(every)
+	if spec.Grace != nil {
+		grace = *spec.Grace
+	}
+
+	c, err := m.ResolveCheck(handle, isUUID)
+	switch {
+	case errors.Is(err, ErrCheckNotFound) && create && !isUUID:
+	case err != nil:
+		slog.Warn("not syncing the check's period with the interval", "error", err)
+		return
+	case len(c.Schedule) > 0:
+		slog.Warn("not syncing the period of a check with a cron schedule with the interval", "schedule", c.Schedule)
+		return
+	case spec.IsEmpty() && c.Timeout == timeout && c.Grace == grace:
+		return
+	}
+
+	spec.Timeout, spec.Grace = &timeout, &grace
+}
@@ -0,0 +1,85 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+func TestScheduleFor(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		every          time.Duration
+		timeout, grace int
+	}{
+		{time.Hour, 3600, 360},
+		{30 * time.Second, 60, 60},
+		{5 * time.Minute, 300, 60},
+		{2 * 365 * 24 * time.Hour, 365 * 24 * 3600, 73 * 24 * 3600},
+	} {
+		if timeout, grace := ScheduleFor(tc.every); timeout != tc.timeout || grace != tc.grace {
+			t.Errorf("%v: expected %d, %d, got %d, %d", tc.every, tc.timeout, tc.grace, timeout, grace)
+		}
+	}
+}
+
+// Tests if ScheduleSpec syncs only existing checks with a simple period that
+// differs from the interval, and leaves cron checks and explicit schedules
+// alone.
+func TestScheduleSpec(t *testing.T) {
+	t.Parallel()
+
+	ptr := func(v int) *int { return &v }
+	str := func(v string) *string { return &v }
+
+	for _, tc := range []struct {
+		desc           string
+		schedule       string // of the existing check
+		handle         string
+		isUUID, create bool
+		every          time.Duration
+		spec           CheckSpec
+		timeout, grace *int // expected in spec
+	}{
+		{desc: "matching period", handle: TestCheckUUID, isUUID: true, every: 24 * time.Hour},
+		{desc: "matching period with other attributes", handle: TestCheckUUID, isUUID: true, every: 24 * time.Hour, spec: CheckSpec{Name: str("Backups")}, timeout: ptr(86400), grace: ptr(8640)},
+		{desc: "differing period", handle: TestCheckUUID, isUUID: true, every: time.Hour, timeout: ptr(3600), grace: ptr(360)},
+		{desc: "slug handle", handle: "pk/backups", every: time.Hour, timeout: ptr(3600), grace: ptr(360)},
+		{desc: "explicit grace", handle: TestCheckUUID, isUUID: true, every: time.Hour, spec: CheckSpec{Grace: ptr(100)}, timeout: ptr(3600), grace: ptr(100)},
+		{desc: "explicit period", handle: TestCheckUUID, isUUID: true, every: time.Hour, spec: CheckSpec{Timeout: ptr(60)}, timeout: ptr(60)},
+		{desc: "cron schedule", schedule: "0 3 * * *", handle: TestCheckUUID, isUUID: true, every: time.Hour},
+		{desc: "cron schedule with other attributes", schedule: "0 3 * * *", handle: TestCheckUUID, isUUID: true, every: time.Hour, spec: CheckSpec{Name: str("Backups")}},
+		{desc: "missing uuid", handle: "00000000-0000-4000-8000-000000000000", isUUID: true, create: true, every: time.Hour},
+		{desc: "missing slug", handle: "pk/missing", every: time.Hour},
+		{desc: "missing slug with create", handle: "pk/missing", create: true, every: time.Hour, timeout: ptr(3600), grace: ptr(360)},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			f, m := newFakeManagementAPI(t)
+			c := f.checks[TestCheckUUID]
+			c.Grace = 8640
+			if len(tc.schedule) > 0 {
+				c.Schedule, c.Timeout = tc.schedule, 0
+			}
+
+			spec := tc.spec
+			ScheduleSpec(m, tc.handle, tc.isUUID, tc.every, tc.create, &spec)
+
+			eq := func(a, b *int) bool { return a == nil && b == nil || a != nil && b != nil && *a == *b }
+			if !eq(spec.Timeout, tc.timeout) || !eq(spec.Grace, tc.grace) {
+				show := func(p *int) any {
+					if p == nil {
+						return nil
+					}
+					return *p
+				}
+				t.Errorf("expected timeout %v and grace %v, got %v and %v", show(tc.timeout), show(tc.grace), show(spec.Timeout), show(spec.Grace))
+			}
+		})
+	}
+}
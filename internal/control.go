@@ -0,0 +1,241 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Commands accepted on the control socket.
+const (
+	ControlRunNow           = "run-now"            // Run the command right away and reset the interval
+	ControlPause            = "pause"              // Skip the scheduled runs until resumed
+	ControlResume           = "resume"             // Resume the scheduled runs
+	ControlStatus           = "status"             // Only report the status
+	ControlStopAfterCurrent = "stop-after-current" // Exit after the run in progress, or right away if idle
+	ControlAttach           = "attach"             // Stream the output of the run in progress
+)
+
+// ControlCommands are the commands accepted on the control socket.
+var ControlCommands = []string{ControlRunNow, ControlPause, ControlResume, ControlStatus, ControlStopAfterCurrent, ControlAttach}
+
+// ErrControlSocketInUse is returned by ListenControl if another process is
+// serving the control socket.
+var ErrControlSocketInUse = errors.New("control socket is in use by another process")
+
+// ErrControlDirUnsafe is returned by ListenControl and SendControl if the
+// directory of the control socket could be written to by other users.
+var ErrControlDirUnsafe = errors.New("control socket directory is unsafe")
+
+// InstanceStatus is the status of a runitor instance in periodic mode, as
+// reported over the control socket.
+type InstanceStatus struct {
+	Name             string     `json:"name"`
+	PID              int        `json:"pid"`
+	Running          bool       `json:"running"`            // A run is in progress
+	Paused           bool       `json:"paused"`             // Scheduled runs are skipped
+	StopAfterCurrent bool       `json:"stop_after_current"` // Exits after the run in progress
+	NextRun          time.Time  `json:"next_run,omitzero"`  // Zero if paused or stopping
+	LastRun          *RunResult `json:"last_run,omitempty"`
+}
+
+// ControlReply is the reply of a control socket handler to a command.
+type ControlReply struct {
+	Status *InstanceStatus
+
+	// Stream, if not nil, writes a stream following the status until it
+	// returns, like the output of the run in progress.
+	Stream func(w io.Writer) error
+}
+
+// controlResponse is the reply to a control command on the wire.
+type controlResponse struct {
+	Error  string          `json:"error,omitempty"`
+	Status *InstanceStatus `json:"status,omitempty"`
+}
+
+// ControlDir returns the directory control sockets are created in:
+// $XDG_RUNTIME_DIR/runitor if XDG_RUNTIME_DIR is set, or a directory named
+// after the user id in the temporary directory otherwise.
+func ControlDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); len(dir) > 0 {
+		return filepath.Join(dir, "runitor")
+	}
+
+	return filepath.Join(os.TempDir(), "runitor-"+strconv.Itoa(os.Getuid()))
+}
+
+// ControlSocketPath returns the path of the control socket of the runitor
+// instance called name in dir. Returns an error if name cannot be a file
+// name.
+func ControlSocketPath(dir, name string) (string, error) {
+	if len(name) == 0 || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid instance name %q", name)
+	}
+
+	return filepath.Join(dir, name+".sock"), nil
+}
+
+// ListenControl listens on the control socket at path, creating its directory
+// accessible only by the user if needed. Returns ErrControlDirUnsafe if the
+// directory exists but isn't private to the user.
+//
+// A socket file left behind by an exited process is replaced. Returns
+// ErrControlSocketInUse if another process is serving the socket.
+func ListenControl(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	// The socket is only reachable through the directory, so it's safe to
+	// listen before restricting the socket itself.
+	if err := checkControlDir(dir); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil && errors.Is(err, syscall.EADDRINUSE) {
+		if conn, derr := net.Dial("unix", path); derr == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s: %w", path, ErrControlSocketInUse)
+		}
+
+		// Stale socket file. Anything else is left alone.
+		if fi, err := os.Lstat(path); err != nil {
+			return nil, err
+		} else if fi.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("%s: exists and isn't a socket", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+		ln, err = net.Listen("unix", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+// ServeControl accepts connections on ln until it's closed, and replies to
+// each command received with the status returned by handle, or its error,
+// followed by the reply's stream, if any. Each connection carries one command
+// on a line.
+func ServeControl(ln net.Listener, handle func(cmd string) (*ControlReply, error)) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+			var (
+				resp  controlResponse
+				reply *ControlReply
+			)
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err == nil {
+				reply, err = handle(strings.TrimSpace(line))
+			}
+			if err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.Status = reply.Status
+			}
+
+			if err := json.NewEncoder(conn).Encode(&resp); err != nil || reply == nil || reply.Stream == nil {
+				return
+			}
+
+			// Streams last as long as they need.
+			conn.SetDeadline(time.Time{})
+			reply.Stream(conn)
+		}()
+	}
+}
+
+// SendControl sends cmd to the control socket at path and returns the
+// status of the instance in reply. Returns ErrControlDirUnsafe if the
+// directory of the socket isn't private to the user.
+func SendControl(path, cmd string) (*InstanceStatus, error) {
+	return sendControl(path, cmd, nil)
+}
+
+// AttachControl attaches to the instance serving the control socket at path
+// and copies the output of its run in progress to w, from the output captured
+// so far until the command exits.
+func AttachControl(path string, w io.Writer) error {
+	_, err := sendControl(path, ControlAttach, w)
+	return err
+}
+
+// sendControl sends cmd to the control socket at path and returns the status
+// in reply. The stream following the status is copied to stream, if not nil.
+func sendControl(path, cmd string, stream io.Writer) (*InstanceStatus, error) {
+	if err := checkControlDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if _, err := fmt.Fprintln(conn, cmd); err != nil {
+		return nil, err
+	}
+
+	var resp controlResponse
+	dec := json.NewDecoder(conn)
+	if err := dec.Decode(&resp); err != nil {
+		return nil, fmt.Errorf("reading reply: %w", err)
+	}
+
+	if len(resp.Error) > 0 {
+		return nil, errors.New(resp.Error)
+	}
+
+	if stream != nil {
+		conn.SetDeadline(time.Time{})
+
+		// Skip the newline ending the status.
+		r := io.MultiReader(dec.Buffered(), conn)
+		if _, err := io.ReadFull(r, make([]byte, 1)); err != nil {
+			return resp.Status, nil
+		}
+
+		if _, err := io.Copy(stream, r); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp.Status, nil
+}
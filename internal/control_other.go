@@ -0,0 +1,26 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+
+//go:build !unix
+
+package internal
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkControlDir returns an error unless dir is a directory. Its owner and
+// permissions aren't checked on platforms without Unix style permissions.
+func checkControlDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	if !fi.IsDir() {
+		return fmt.Errorf("%s: %w: not a directory", dir, ErrControlDirUnsafe)
+	}
+
+	return nil
+}
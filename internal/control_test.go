@@ -0,0 +1,100 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if commands sent with SendControl get the status or the error
+// returned by the handler, and if a socket file left behind is replaced but
+// one in use isn't.
+func TestControl(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path, err := ControlSocketPath(filepath.Join(dir, "runitor"), "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave a stale socket file behind.
+	stale, err := ListenControl(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := ListenControl(path)
+	if err != nil {
+		t.Fatalf("expected stale socket to be replaced, got %v", err)
+	}
+	defer ln.Close()
+
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("expected socket to be accessible only by the user, got %v, %v", fi.Mode(), err)
+	}
+
+	if _, err := ListenControl(path); !errors.Is(err, ErrControlSocketInUse) {
+		t.Errorf("expected ErrControlSocketInUse, got %v", err)
+	}
+
+	var got []string
+	go ServeControl(ln, func(cmd string) (*ControlReply, error) {
+		got = append(got, cmd)
+		switch cmd {
+		case ControlPause:
+			return &ControlReply{Status: &InstanceStatus{Name: "backup", Paused: true, LastRun: &RunResult{ExitCode: 3}}}, nil
+		case ControlAttach:
+			return &ControlReply{Status: &InstanceStatus{Running: true}, Stream: func(w io.Writer) error {
+				_, err := io.WriteString(w, "output\n")
+				return err
+			}}, nil
+		}
+		return nil, errors.New("unknown command")
+	})
+
+	status, err := SendControl(path, ControlPause)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Paused || status.LastRun == nil || status.LastRun.ExitCode != 3 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	if _, err := SendControl(path, "bogus"); err == nil || err.Error() != "unknown command" {
+		t.Errorf("expected the handler's error, got %v", err)
+	}
+
+	var out strings.Builder
+	if err := AttachControl(path, &out); err != nil || out.String() != "output\n" {
+		t.Errorf("expected the streamed output, got %q, %v", out.String(), err)
+	}
+
+	if len(got) != 3 || got[0] != ControlPause || got[1] != "bogus" || got[2] != ControlAttach {
+		t.Errorf("unexpected commands received: %q", got)
+	}
+}
+
+func TestControlSocketPath(t *testing.T) {
+	t.Parallel()
+
+	if p, err := ControlSocketPath("/run/runitor", "backup.sh"); err != nil || p != "/run/runitor/backup.sh.sock" {
+		t.Errorf("unexpected path %q, %v", p, err)
+	}
+
+	for _, name := range []string{"", ".", "..", "a/b"} {
+		if _, err := ControlSocketPath("/run/runitor", name); err == nil {
+			t.Errorf("expected name %q to be invalid", name)
+		}
+	}
+}
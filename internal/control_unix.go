@@ -0,0 +1,34 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+
+//go:build unix
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkControlDir returns an error unless dir is a directory, not a symlink,
+// owned by the user and accessible only by them, so no one else can replace
+// the sockets in it.
+func checkControlDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	switch {
+	case !fi.IsDir():
+		return fmt.Errorf("%s: %w: not a directory", dir, ErrControlDirUnsafe)
+	case !ok || int(st.Uid) != os.Getuid():
+		return fmt.Errorf("%s: %w: not owned by the user", dir, ErrControlDirUnsafe)
+	case fi.Mode().Perm() != 0o700:
+		return fmt.Errorf("%s: %w: mode is %04o, not 0700", dir, ErrControlDirUnsafe, fi.Mode().Perm())
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+
+//go:build unix
+
+package internal_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if control sockets are refused in directories others could write to
+// or that are symlinks, and if a file that isn't a socket is never replaced.
+func TestControlDirUnsafe(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	shared := filepath.Join(dir, "shared")
+	if err := os.Mkdir(shared, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(shared, 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	private := filepath.Join(dir, "private")
+	if err := os.Mkdir(private, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(private, link); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range []string{shared, link} {
+		path := filepath.Join(d, "backup.sock")
+
+		if ln, err := ListenControl(path); !errors.Is(err, ErrControlDirUnsafe) {
+			if err == nil {
+				ln.Close()
+			}
+			t.Errorf("%s: expected ListenControl to return ErrControlDirUnsafe, got %v", d, err)
+		}
+
+		if _, err := SendControl(path, ControlStatus); !errors.Is(err, ErrControlDirUnsafe) {
+			t.Errorf("%s: expected SendControl to return ErrControlDirUnsafe, got %v", d, err)
+		}
+	}
+
+	path := filepath.Join(private, "backup.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if ln, err := ListenControl(path); err == nil {
+		ln.Close()
+		t.Error("expected a file that isn't a socket not to be replaced")
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "data" {
+		t.Errorf("expected the file to be left alone, got %q, %v", b, err)
+	}
+}
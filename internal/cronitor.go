@@ -0,0 +1,125 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Default Cronitor telemetry API address.
+	DefaultCronitorURL = "https://cronitor.link"
+	// CronitorMessageLimit is the maximum length in bytes of the message
+	// parameter sent to Cronitor. Longer outputs keep their tail.
+	CronitorMessageLimit = 2000
+)
+
+// CronitorPinger implements Pinger for Cronitor's telemetry API. The check
+// handle is in "api-key/monitor-key" form, like a Healthchecks ping key and
+// slug handle.
+//
+// Start pings are sent with run state, success pings and zero exit codes with
+// complete, and failures and nonzero exit codes with fail. The run id is sent
+// as the series to correlate the events of a run. Cronitor has no log events,
+// so these pings aren't sent.
+//
+// https://cronitor.io/docs/telemetry-api
+type CronitorPinger struct {
+	// BaseURL is the address of Cronitor telemetry API.
+	BaseURL string
+
+	// Hostname is reported as the host of the events.
+	Hostname string
+
+	// Client sends the events.
+	Client *APIClient
+}
+
+// PingStart sends a run event for the monitor in handle.
+func (c *CronitorPinger) PingStart(handle string, params PingParams) (*InstanceConfig, error) {
+	return c.send(handle, params, "run", nil)
+}
+
+// PingLog is a no-op. Cronitor has no log events.
+func (c *CronitorPinger) PingLog(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return &InstanceConfig{}, nil
+}
+
+// PingSuccess sends a complete event for the monitor in handle.
+func (c *CronitorPinger) PingSuccess(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return c.send(handle, params, "complete", body)
+}
+
+// PingFail sends a fail event for the monitor in handle.
+func (c *CronitorPinger) PingFail(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return c.send(handle, params, "fail", body)
+}
+
+// PingExitCode sends a complete event for a zero exitCode and a fail event
+// otherwise for the monitor in handle.
+func (c *CronitorPinger) PingExitCode(handle string, params PingParams, exitCode int, body io.ReadSeeker) (*InstanceConfig, error) {
+	params.ExitCode = Some(exitCode)
+	if exitCode != 0 {
+		return c.send(handle, params, "fail", body)
+	}
+
+	return c.send(handle, params, "complete", body)
+}
+
+func (c *CronitorPinger) send(handle string, params PingParams, state string, body io.ReadSeeker) (*InstanceConfig, error) {
+	apiKey, monitor, ok := strings.Cut(handle, "/")
+	if !ok {
+		return nil, errors.New("cronitor handle must be in 'api-key/monitor-key' form")
+	}
+
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path, err = url.JoinPath(u.Path, "p", apiKey, monitor)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("state", state)
+	if len(params.RunId) > 0 {
+		q.Set("series", params.RunId)
+	}
+	if code, ok := params.ExitCode.Get(); ok {
+		q.Set("status_code", strconv.Itoa(code))
+	}
+	if params.Duration > 0 {
+		q.Set("duration", strconv.FormatFloat(params.Duration.Seconds(), 'f', 3, 64))
+	}
+	if len(c.Hostname) > 0 {
+		q.Set("host", c.Hostname)
+	}
+
+	if body != nil {
+		out, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if msg := outputTail(string(out), CronitorMessageLimit); len(msg) > 0 {
+			q.Set("message", msg)
+		}
+	}
+
+	u.RawQuery = q.Encode()
+
+	resp, err := c.Client.Request("GET", u.String(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	return &InstanceConfig{}, nil
+}
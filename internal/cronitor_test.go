@@ -0,0 +1,64 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if CronitorPinger maps pings to telemetry events with the run id as
+// the series and skips log pings.
+func TestCronitorPinger(t *testing.T) {
+	t.Parallel()
+
+	var reqs []*url.URL
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs = append(reqs, r.URL)
+	}))
+	defer ts.Close()
+
+	c := &CronitorPinger{BaseURL: ts.URL, Hostname: "host", Client: &APIClient{Client: ts.Client()}}
+
+	if _, err := c.PingStart(TestHandle, TestPingParamsWithRID); err != nil {
+		t.Fatalf("PingStart failed: %v", err)
+	}
+
+	if _, err := c.PingLog(TestHandle, TestPingParamsWithRID, bytes.NewReader(TestPingBody)); err != nil {
+		t.Fatalf("PingLog failed: %v", err)
+	}
+
+	params := PingParams{RunId: TestRunId, Duration: 1500 * time.Millisecond}
+	if _, err := c.PingExitCode(TestHandle, params, 3, bytes.NewReader(TestPingBody)); err != nil {
+		t.Fatalf("PingExitCode failed: %v", err)
+	}
+
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(reqs))
+	}
+
+	expected := []url.Values{
+		{"state": {"run"}, "series": {TestRunId}, "host": {"host"}},
+		{"state": {"fail"}, "series": {TestRunId}, "host": {"host"}, "status_code": {"3"}, "duration": {"1.500"}, "message": {string(TestPingBody)}},
+	}
+
+	for i, u := range reqs {
+		if u.Path != "/p/pingKey/testHandle" {
+			t.Errorf("unexpected path %s", u.Path)
+		}
+
+		if got := u.Query().Encode(); got != expected[i].Encode() {
+			t.Errorf("expected query %s, got %s", expected[i].Encode(), got)
+		}
+	}
+
+	if _, err := c.PingSuccess("no-api-key", params, nil); err == nil {
+		t.Error("expected an error for handle without an API key")
+	}
+}
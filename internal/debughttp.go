@@ -0,0 +1,147 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AlwaysRedactedHeaders are the request headers whose values DebugTransport
+// never logs.
+var AlwaysRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// DebugTransport is an http.RoundTripper logging the requests sent through
+// it, their responses, and the timing of the DNS lookup, connection, and TLS
+// handshake phases, with secrets redacted.
+type DebugTransport struct {
+	// Base sends the requests. If nil, http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Logger to log to at info level. If nil, slog's default logger.
+	Logger *slog.Logger
+
+	// Secrets are redacted from the logged URLs, like the ping key. See
+	// RedactURL.
+	Secrets []string
+
+	// RedactHeaders are the names of the request headers whose values are
+	// redacted, in addition to AlwaysRedactedHeaders.
+	RedactHeaders []string
+}
+
+func (t *DebugTransport) logger() *slog.Logger {
+	if t.Logger != nil {
+		return t.Logger
+	}
+
+	return slog.Default()
+}
+
+// headerAttr returns header as a group of attributes, with the values of
+// the headers named in redact replaced with asterisks.
+func headerAttr(header http.Header, redact []string) slog.Attr {
+	var attrs []any
+	for _, k := range slices.Sorted(maps.Keys(header)) {
+		v := any(header[k])
+		if len(header[k]) == 1 {
+			v = header[k][0]
+		}
+		if slices.ContainsFunc(redact, func(name string) bool { return http.CanonicalHeaderKey(name) == k }) {
+			v = "***"
+		}
+		attrs = append(attrs, slog.Any(k, v))
+	}
+
+	return slog.Group("header", attrs...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	logger := t.logger().With("method", req.Method, "url", RedactURL(req.URL.String(), t.Secrets))
+	redact := append(slices.Clip(AlwaysRedactedHeaders), t.RedactHeaders...)
+	logger.Info("http request", headerAttr(req.Header, redact))
+
+	var (
+		mu            sync.Mutex
+		dnsStart      time.Time
+		connectStarts = make(map[string]time.Time)
+		tlsStart      time.Time
+	)
+
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			logger.Info("http connection", "addr", info.Conn.RemoteAddr().String(), "reused", info.Reused)
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			attrs := []any{"duration", time.Since(dnsStart)}
+			if info.Err != nil {
+				attrs = append(attrs, "error", info.Err)
+			} else {
+				addrs := make([]string, len(info.Addrs))
+				for i, a := range info.Addrs {
+					addrs[i] = a.String()
+				}
+				attrs = append(attrs, "addrs", addrs)
+			}
+			logger.Info("http dns", attrs...)
+		},
+		// Connections to multiple addresses may be dialed concurrently.
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			connectStarts[addr] = time.Now()
+			mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			d := time.Since(connectStarts[addr])
+			mu.Unlock()
+
+			attrs := []any{"addr", addr, "duration", d}
+			if err != nil {
+				attrs = append(attrs, "error", err)
+			}
+			logger.Info("http connect", attrs...)
+		},
+		TLSHandshakeStart: func() {
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			attrs := []any{"duration", time.Since(tlsStart)}
+			if err != nil {
+				attrs = append(attrs, "error", err)
+			} else {
+				attrs = append(attrs, "version", tls.VersionName(cs.Version), "resumed", cs.DidResume, "protocol", cs.NegotiatedProtocol)
+			}
+			logger.Info("http tls handshake", attrs...)
+		},
+		GotFirstResponseByte: func() {
+			logger.Info("http first response byte", "duration", time.Since(start))
+		},
+	}
+
+	resp, err := base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err != nil {
+		logger.Info("http request failed", "duration", time.Since(start), "error", err)
+		return nil, err
+	}
+
+	logger.Info("http response", "status", resp.StatusCode, "duration", time.Since(start), headerAttr(resp.Header, nil))
+
+	return resp, nil
+}
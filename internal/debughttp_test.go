@@ -0,0 +1,72 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if DebugTransport logs requests, responses, and TLS handshakes with
+// secrets redacted, and if APIClient logs its retry decisions.
+func TestDebugTransport(t *testing.T) {
+	t.Parallel()
+
+	var reqs atomic.Int32
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first request.
+		if reqs.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set(PingBodyLimitHeader, "1000")
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	hc := ts.Client()
+	hc.Transport = &DebugTransport{Base: hc.Transport, Logger: logger, Secrets: []string{"pingKey"}, RedactHeaders: []string{"x-token"}}
+	c := &APIClient{
+		BaseURL:    ts.URL,
+		Client:     hc,
+		Retries:    1,
+		Backoff:    time.Millisecond,
+		ReqHeaders: map[string]string{"X-Token": "tokenValue"},
+		Logger:     logger,
+	}
+
+	if _, err := c.PingStart("pingKey/testHandle", TestPingParamsNone); err != nil {
+		t.Fatalf("PingStart failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, s := range []string{"pingKey", "tokenValue"} {
+		if strings.Contains(out, s) {
+			t.Errorf("expected %q to be redacted, got:\n%s", s, out)
+		}
+	}
+
+	for _, s := range []string{
+		`msg="http request" method=POST url=` + ts.URL + `/***/testHandle/start header.Content-Type=text/plain`,
+		`header.X-Token=***`,
+		`msg="http tls handshake"`,
+		`msg="http response" method=POST url=` + ts.URL + `/***/testHandle/start status=503`,
+		`msg="retrying request" method=POST attempt=2 backoff=1ms reason="503 Service Unavailable"`,
+		`status=200`,
+		`header.Ping-Body-Limit=1000`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected log to contain %q, got:\n%s", s, out)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseDotenv parses environment variable assignments in dotenv format and
+// returns them in "key=value" form, in the order they appear.
+//
+// Supported syntax:
+//
+//	# comment
+//	KEY=value            # unquoted values are trimmed, trailing comments are removed
+//	export KEY=value     # optional export prefix
+//	KEY='literal value'  # no escape sequences within single quotes
+//	KEY="line\nbreak"    # \n, \r, \t, \", and \\ escape sequences within double quotes
+//
+// Variable references are not expanded.
+func ParseDotenv(r io.Reader) ([]string, error) {
+	var env []string
+
+	s := bufio.NewScanner(r)
+	for lineno := 1; s.Scan(); lineno++ {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		key, val, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validEnvKey(key) {
+			return nil, fmt.Errorf("line %d: not in KEY=value format", lineno)
+		}
+
+		val, err := parseDotenvValue(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineno, err)
+		}
+
+		env = append(env, key+"="+val)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+func parseDotenvValue(val string) (string, error) {
+	if len(val) == 0 {
+		return val, nil
+	}
+
+	switch q := val[0]; q {
+	case '\'', '"':
+		end := closingQuote(val, q)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated %c quote", q)
+		}
+
+		if rest := strings.TrimSpace(val[end+1:]); len(rest) > 0 && rest[0] != '#' {
+			return "", fmt.Errorf("unexpected characters after closing quote")
+		}
+
+		if q == '\'' {
+			return val[1:end], nil
+		}
+
+		return unescapeDotenv(val[1:end]), nil
+
+	default:
+		if i := strings.Index(val, " #"); i >= 0 {
+			val = val[:i]
+		}
+
+		return strings.TrimSpace(val), nil
+	}
+}
+
+// closingQuote returns the index of the quote character q closing the string
+// opened at s[0], or -1 if there's none. Backslash escapes the quote character
+// in double quoted strings.
+func closingQuote(s string, q byte) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+
+	return -1
+}
+
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+func validEnvKey(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+
+	for i, c := range key {
+		switch {
+		case c == '_', 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+func TestParseDotenv(t *testing.T) {
+	t.Parallel()
+
+	const input = `
+# comment
+PLAIN=value
+export EXPORTED=yes
+  SPACED  =  padded value
+TRAILING=value # comment
+HASH=val#ue
+EMPTY=
+SINGLE='not # a comment'
+SINGLE_RAW='a\nb'
+DOUBLE="line\nbreak \"quoted\" \\ \$"
+DOUBLE_COMMENT="x" # comment
+EQUALS=a=b
+`
+
+	expected := []string{
+		"PLAIN=value",
+		"EXPORTED=yes",
+		"SPACED=padded value",
+		"TRAILING=value",
+		"HASH=val#ue",
+		"EMPTY=",
+		"SINGLE=not # a comment",
+		"SINGLE_RAW=a\\nb",
+		"DOUBLE=line\nbreak \"quoted\" \\ \\$",
+		"DOUBLE_COMMENT=x",
+		"EQUALS=a=b",
+	}
+
+	env, err := ParseDotenv(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("expected successful parse, got error: %v", err)
+	}
+
+	if !slices.Equal(env, expected) {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, env)
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"no equals":         "JUSTKEY",
+		"empty key":         "=value",
+		"invalid key":       "1KEY=value",
+		"key with space":    "MY KEY=value",
+		"unterminated":      `KEY="value`,
+		"after quote":       `KEY='value' extra`,
+		"escaped end quote": `KEY="value\"`,
+	}
+
+	for name, input := range testCases {
+		if _, err := ParseDotenv(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected error for input %q", name, input)
+		}
+	}
+}
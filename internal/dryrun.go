@@ -0,0 +1,88 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DryRunPinger implements Pinger by printing the pings an APIClient would send
+// instead of sending them. The ping key and most of the check UUID are
+// redacted from the printed URLs.
+type DryRunPinger struct {
+	// Client is the APIClient whose requests are printed. It doesn't send
+	// any requests.
+	Client *APIClient
+
+	// W is where the pings are printed.
+	W io.Writer
+}
+
+// PingStart prints a start ping for the check handle.
+func (d *DryRunPinger) PingStart(handle string, params PingParams) (*InstanceConfig, error) {
+	return d.print(handle, params, "start", nil)
+}
+
+// PingSuccess prints a success ping for the check handle with body.
+func (d *DryRunPinger) PingSuccess(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return d.print(handle, params, "", body)
+}
+
+// PingFail prints a failure ping for the check handle with body.
+func (d *DryRunPinger) PingFail(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return d.print(handle, params, "fail", body)
+}
+
+// PingLog prints a logging only ping for the check handle with body.
+func (d *DryRunPinger) PingLog(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return d.print(handle, params, "log", body)
+}
+
+// PingExitCode prints an exit code ping for the check handle with body.
+func (d *DryRunPinger) PingExitCode(handle string, params PingParams, exitCode int, body io.ReadSeeker) (*InstanceConfig, error) {
+	return d.print(handle, params, fmt.Sprintf("%d", exitCode), body)
+}
+
+func (d *DryRunPinger) print(handle string, params PingParams, typePath string, body io.ReadSeeker) (*InstanceConfig, error) {
+	u, err := d.Client.pingURL(RedactHandle(handle), params, typePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the redaction marks readable instead of percent-encoded.
+	u.RawPath = u.Path
+
+	var b strings.Builder
+	q := u.Query()
+	u.RawQuery = ""
+	fmt.Fprintf(&b, "POST %s\n", u.Redacted())
+
+	for _, k := range slices.Sorted(maps.Keys(q)) {
+		for _, v := range q[k] {
+			fmt.Fprintf(&b, "  %s=%s\n", k, v)
+		}
+	}
+
+	if body != nil {
+		p, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&b, "  body (%d bytes)\n", len(p))
+		if len(p) > 0 {
+			b.Write(p)
+			if p[len(p)-1] != '\n' {
+				b.WriteByte('\n')
+			}
+		}
+	}
+
+	_, err = io.WriteString(d.W, b.String())
+
+	return &InstanceConfig{}, err
+}
@@ -0,0 +1,43 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if DryRunPinger prints the pings with the ping key redacted, the query
+// parameters, and the body.
+func TestDryRunPinger(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	p := &DryRunPinger{Client: &APIClient{BaseURL: "https://hc.example/ping"}, W: &out}
+
+	if _, err := p.PingStart(TestHandle, TestPingParamsWithRIDCreate); err != nil {
+		t.Fatalf("PingStart failed: %v", err)
+	}
+
+	if _, err := p.PingExitCode(TestHandle, TestPingParamsNone, 3, bytes.NewReader(TestPingBody)); err != nil {
+		t.Fatalf("PingExitCode failed: %v", err)
+	}
+
+	exp := "POST https://hc.example/ping/***/testHandle/start\n" +
+		"  create=1\n" +
+		"  rid=" + TestRunId + "\n" +
+		"POST https://hc.example/ping/***/testHandle/3\n" +
+		"  body (14 bytes)\n" +
+		string(TestPingBody) + "\n"
+
+	if out.String() != exp {
+		t.Errorf("expected output:\n%s\ngot:\n%s", exp, out.String())
+	}
+
+	if strings.Contains(out.String(), "pingKey") {
+		t.Error("expected ping key to be redacted")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// EnvConfig describes how the command's environment is composed.
+type EnvConfig struct {
+	Clear bool     // Start with an empty environment instead of runitor's
+	Keep  []string // Variables carried over from runitor's environment, even if listed in Scrub
+	Scrub []string // Variables removed from runitor's environment when Clear is not set
+	Files []string // Dotenv files read in order
+	Vars  []string // Variables in "key=value" form, applied last
+}
+
+// Environ composes the command's environment from runitor's own environment
+// (less the scrubbed variables that aren't kept), the identity in ra if
+// non-nil, the dotenv files, and variables, in this order. Later assignments
+// to a variable override earlier ones.
+//
+// Returns nil if the command should inherit runitor's environment as is.
+func (ec *EnvConfig) Environ(ra *RunAs) ([]string, error) {
+	var env []string
+
+	if ec.Clear {
+		env = []string{}
+		for _, k := range ec.Keep {
+			if v, ok := os.LookupEnv(k); ok {
+				env = append(env, k+"="+v)
+			}
+		}
+	} else {
+		scrub := slices.DeleteFunc(slices.Clone(ec.Scrub), func(k string) bool {
+			return !envIsSet(k) || slices.Contains(ec.Keep, k)
+		})
+		if len(scrub) > 0 {
+			env = slices.DeleteFunc(os.Environ(), func(kv string) bool {
+				k, _, _ := strings.Cut(kv, "=")
+				return slices.Contains(scrub, k)
+			})
+		}
+	}
+
+	var extra []string
+	if ra != nil {
+		extra = append(extra, ra.Env()...)
+	}
+
+	for _, fn := range ec.Files {
+		f, err := os.Open(fn)
+		if err != nil {
+			return nil, err
+		}
+
+		fenv, err := ParseDotenv(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		extra = append(extra, fenv...)
+	}
+
+	extra = append(extra, ec.Vars...)
+
+	if env == nil {
+		if len(extra) == 0 {
+			return nil, nil
+		}
+		env = os.Environ()
+	}
+
+	// os/exec uses the last value for duplicate keys.
+	return append(env, extra...), nil
+}
+
+func envIsSet(key string) bool {
+	_, ok := os.LookupEnv(key)
+	return ok
+}
+
+// EnvKeys returns the sorted, unique variable names in env.
+func EnvKeys(env []string) []string {
+	keys := make([]string, 0, len(env))
+	for _, kv := range env {
+		if k, _, _ := strings.Cut(kv, "="); len(k) > 0 {
+			keys = append(keys, k)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return slices.Compact(keys)
+}
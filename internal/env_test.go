@@ -0,0 +1,122 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// lookupEnv returns the value of key in env the way os/exec resolves
+// duplicates, by taking the last one.
+func lookupEnv(env []string, key string) (string, bool) {
+	for _, kv := range slices.Backward(env) {
+		if k, v, _ := strings.Cut(kv, "="); k == key {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// Tests if the command inherits runitor's environment as is when there's
+// nothing to scrub or add.
+func TestEnvironInherit(t *testing.T) {
+	t.Setenv("RUNITOR_TEST_SECRET", "")
+	os.Unsetenv("RUNITOR_TEST_SECRET")
+
+	ec := &EnvConfig{Scrub: []string{"RUNITOR_TEST_SECRET"}}
+	env, err := ec.Environ(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env != nil {
+		t.Errorf("expected the environment to be inherited, got %q", env)
+	}
+}
+
+// Tests if scrubbed variables are removed unless they're kept.
+func TestEnvironScrub(t *testing.T) {
+	t.Setenv("RUNITOR_TEST_SECRET", "s3cret")
+	t.Setenv("RUNITOR_TEST_KEPT", "kept")
+	t.Setenv("RUNITOR_TEST_OTHER", "other")
+
+	ec := &EnvConfig{
+		Scrub: []string{"RUNITOR_TEST_SECRET", "RUNITOR_TEST_KEPT"},
+		Keep:  []string{"RUNITOR_TEST_KEPT"},
+	}
+	env, err := ec.Environ(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := lookupEnv(env, "RUNITOR_TEST_SECRET"); ok {
+		t.Errorf("expected RUNITOR_TEST_SECRET to be scrubbed, got %q", v)
+	}
+
+	for k, want := range map[string]string{"RUNITOR_TEST_KEPT": "kept", "RUNITOR_TEST_OTHER": "other"} {
+		if v, _ := lookupEnv(env, k); v != want {
+			t.Errorf("expected %s=%s, got %q", k, want, v)
+		}
+	}
+}
+
+// Tests if a cleared environment has only the kept variables that are set,
+// regardless of scrubbing.
+func TestEnvironClear(t *testing.T) {
+	t.Setenv("RUNITOR_TEST_SECRET", "s3cret")
+	t.Setenv("RUNITOR_TEST_OTHER", "other")
+
+	ec := &EnvConfig{
+		Clear: true,
+		Keep:  []string{"RUNITOR_TEST_SECRET", "RUNITOR_TEST_MISSING"},
+		Scrub: []string{"RUNITOR_TEST_SECRET"},
+	}
+	env, err := ec.Environ(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"RUNITOR_TEST_SECRET=s3cret"}; !slices.Equal(env, want) {
+		t.Errorf("expected %q, got %q", want, env)
+	}
+}
+
+// Tests if the identity, dotenv files, and variables are applied in order
+// over runitor's environment.
+func TestEnvironMerge(t *testing.T) {
+	t.Setenv("RUNITOR_TEST_A", "env")
+	t.Setenv("RUNITOR_TEST_B", "env")
+	t.Setenv("HOME", "/root")
+
+	fn := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(fn, []byte("RUNITOR_TEST_A=file\nRUNITOR_TEST_B=file\nHOME=/file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ec := &EnvConfig{
+		Files: []string{fn},
+		Vars:  []string{"RUNITOR_TEST_B=var"},
+	}
+	env, err := ec.Environ(&RunAs{Username: "backup", HomeDir: "/home/backup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, want := range map[string]string{"RUNITOR_TEST_A": "file", "RUNITOR_TEST_B": "var", "HOME": "/file", "USER": "backup"} {
+		if v, _ := lookupEnv(env, k); v != want {
+			t.Errorf("expected %s=%s, got %q", k, want, v)
+		}
+	}
+
+	ec.Files = []string{filepath.Join(t.TempDir(), "missing.env")}
+	if _, err := ec.Environ(nil); err == nil {
+		t.Error("expected a missing dotenv file to fail")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default ping body limit of the fake server, matching Healthchecks.io's.
+const DefaultFakePingBodyLimit = 100_000
+
+// FakePingsPath is the path the fake server serves the received pings in JSON
+// at.
+const FakePingsPath = "/pings.json"
+
+var slugRe = regexp.MustCompile(`^[-a-zA-Z0-9_]+$`)
+
+// FakePing is a ping received by FakeServer.
+type FakePing struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	UUID      string    `json:"uuid,omitempty"`
+	Slug      string    `json:"slug,omitempty"`
+	Type      string    `json:"type"` // success, start, fail, log or exitcode
+	ExitCode  *int      `json:"exit_code,omitempty"`
+	RunId     string    `json:"rid,omitempty"`
+	Create    bool      `json:"create,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	BodySize  int       `json:"body_size"` // size before truncation
+	Body      string    `json:"body"`
+	Truncated bool      `json:"truncated,omitempty"`
+}
+
+// Check returns the UUID or the slug of the pinged check.
+func (p *FakePing) Check() string {
+	if len(p.UUID) > 0 {
+		return p.UUID
+	}
+
+	return p.Slug
+}
+
+// String returns a one line summary of the ping.
+func (p *FakePing) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s %s", p.Time.Format(time.TimeOnly), p.Method, p.Check(), p.Type)
+	if p.ExitCode != nil {
+		fmt.Fprintf(&b, " %d", *p.ExitCode)
+	}
+	if len(p.RunId) > 0 {
+		fmt.Fprintf(&b, " rid=%s", p.RunId)
+	}
+	if p.Create {
+		b.WriteString(" create=1")
+	}
+	fmt.Fprintf(&b, " body=%dB", p.BodySize)
+	if p.Truncated {
+		b.WriteString(" (truncated)")
+	}
+
+	return b.String()
+}
+
+// FakeServer is a local stand-in for the Healthchecks.io pinging API. It
+// accepts the ping URLs APIClient sends, records them, and serves them back in
+// JSON at FakePingsPath.
+//
+// Every UUID handle and ping key is accepted. Slugs are created on their
+// first ping with create=1.
+type FakeServer struct {
+	// PingBodyLimit is sent in Ping-Body-Limit header of the responses and
+	// the received bodies are truncated to it.
+	PingBodyLimit uint
+
+	// Log, if not nil, gets a line for each received ping.
+	Log io.Writer
+
+	mu      sync.Mutex
+	pings   []FakePing
+	created map[string]bool
+}
+
+// Pings returns the pings received so far.
+func (s *FakeServer) Pings() []FakePing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]FakePing(nil), s.pings...)
+}
+
+func (s *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == FakePingsPath && r.Method == "GET" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Pings())
+		return
+	}
+
+	if r.Method != "GET" && r.Method != "HEAD" && r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, key, err := parseFakePing(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.BodySize = len(body)
+	if s.PingBodyLimit > 0 && uint(len(body)) > s.PingBodyLimit {
+		body, p.Truncated = body[:s.PingBodyLimit], true
+	}
+	p.Body = string(body)
+
+	status, text := http.StatusOK, "OK"
+
+	s.mu.Lock()
+	if len(p.Slug) > 0 && p.Create && !s.created[key+"/"+p.Slug] {
+		if s.created == nil {
+			s.created = make(map[string]bool)
+		}
+		s.created[key+"/"+p.Slug] = true
+		status, text = http.StatusCreated, "OK (created)"
+	}
+	s.pings = append(s.pings, p)
+	s.mu.Unlock()
+
+	if s.Log != nil {
+		fmt.Fprintln(s.Log, p.String())
+	}
+
+	if s.PingBodyLimit > 0 {
+		w.Header().Set(PingBodyLimitHeader, strconv.FormatUint(uint64(s.PingBodyLimit), 10))
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, text)
+}
+
+// parseFakePing parses the ping URL shapes in r: /<uuid>[/<type>] and
+// /<ping-key>/<slug>[/<type>], with rid and create query parameters. It
+// returns the ping and the ping key of a slug handle.
+func parseFakePing(r *http.Request) (p FakePing, key string, err error) {
+	p = FakePing{
+		Time:      time.Now(),
+		Method:    r.Method,
+		Type:      "success",
+		UserAgent: r.UserAgent(),
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if uuid, err := ParseUUID(parts[0]); err == nil {
+		p.UUID, parts = uuid, parts[1:]
+	} else if len(parts) >= 2 && len(parts[0]) > 0 && slugRe.MatchString(parts[1]) {
+		key, p.Slug, parts = parts[0], parts[1], parts[2:]
+	} else {
+		return p, "", fmt.Errorf("invalid url format %q", r.URL.Path)
+	}
+
+	switch {
+	case len(parts) == 0:
+	case len(parts) > 1:
+		return p, "", fmt.Errorf("invalid url format %q", r.URL.Path)
+	case parts[0] == "start" || parts[0] == "fail" || parts[0] == "log":
+		p.Type = parts[0]
+	default:
+		code, err := strconv.Atoi(parts[0])
+		if err != nil || code < 0 || code > 255 {
+			return p, "", fmt.Errorf("invalid ping type %q", parts[0])
+		}
+		p.Type, p.ExitCode = "exitcode", &code
+	}
+
+	q := r.URL.Query()
+	if rid := q.Get("rid"); len(rid) > 0 {
+		if p.RunId, err = ParseUUID(rid); err != nil {
+			return p, "", fmt.Errorf("invalid rid %q", rid)
+		}
+	}
+
+	p.Create = q.Get("create") == "1"
+	if p.Create && len(p.UUID) > 0 {
+		return p, "", errors.New("create is only valid with a slug")
+	}
+
+	return p, key, nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if FakeServer accepts the pings APIClient sends, records them, and
+// serves them back in JSON.
+func TestFakeServer(t *testing.T) {
+	t.Parallel()
+
+	f := &FakeServer{PingBodyLimit: 4}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	c := &APIClient{BaseURL: ts.URL, Client: ts.Client()}
+
+	icfg, err := c.PingStart(TestCheckUUID, TestPingParamsWithRID)
+	if err != nil {
+		t.Fatalf("PingStart failed: %v", err)
+	}
+
+	if limit, ok := icfg.PingBodyLimit.Get(); !ok || limit != 4 {
+		t.Errorf("expected ping body limit of 4, got %v", icfg.PingBodyLimit)
+	}
+
+	if _, err := c.PingExitCode(TestHandle, TestPingParamsWithCreate, 3, bytes.NewReader(TestPingBody)); err != nil {
+		t.Fatalf("PingExitCode failed: %v", err)
+	}
+
+	pings := f.Pings()
+	if len(pings) != 2 {
+		t.Fatalf("expected 2 pings, got %d", len(pings))
+	}
+
+	if p := pings[0]; p.UUID != TestCheckUUID || p.Type != "start" || p.RunId != TestRunId {
+		t.Errorf("unexpected start ping: %+v", p)
+	}
+
+	p := pings[1]
+	if p.Slug != "testHandle" || p.Type != "exitcode" || p.ExitCode == nil || *p.ExitCode != 3 || !p.Create {
+		t.Errorf("unexpected exit code ping: %+v", p)
+	}
+
+	if p.Body != string(TestPingBody[:4]) || p.BodySize != len(TestPingBody) || !p.Truncated {
+		t.Errorf("expected body to be truncated to 4 bytes, got %+v", p)
+	}
+
+	resp, err := http.Get(ts.URL + FakePingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var dump []FakePing
+	if err := json.NewDecoder(resp.Body).Decode(&dump); err != nil || len(dump) != 2 {
+		t.Errorf("expected JSON dump of 2 pings, got %+v, err: %v", dump, err)
+	}
+}
+
+// Tests if FakeServer rejects URLs APIClient wouldn't produce.
+func TestFakeServerInvalidPing(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(&FakeServer{})
+	defer ts.Close()
+
+	paths := []string{
+		"/",
+		"/not-a-uuid",
+		"/" + TestCheckUUID + "/256",
+		"/" + TestCheckUUID + "/start/extra",
+		"/" + TestCheckUUID + "?rid=not-a-uuid",
+		"/" + TestCheckUUID + "?create=1",
+		"/pingKey/bad.slug",
+	}
+
+	for _, p := range paths {
+		resp, err := http.Post(ts.URL+p, "text/plain", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", p, resp.StatusCode)
+		}
+	}
+}
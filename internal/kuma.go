@@ -0,0 +1,116 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+)
+
+// KumaMsgLimit is the maximum length in bytes of the msg parameter sent to
+// Uptime Kuma. Longer outputs keep their tail.
+const KumaMsgLimit = 250
+
+// KumaPinger implements Pinger for Uptime Kuma push monitors. The check handle
+// is the push token of the monitor.
+//
+// Kuma has no notion of start or log events, so these pings aren't sent.
+// Success pings and zero exit codes are pushed as up, failures and nonzero
+// exit codes as down, along with the duration of the run and the tail of the
+// output as the message.
+//
+// https://github.com/louislam/uptime-kuma/wiki/Push-Monitor
+type KumaPinger struct {
+	// BaseURL is the address of the Uptime Kuma instance.
+	BaseURL string
+
+	// Client sends the pushes.
+	Client *APIClient
+}
+
+// PingStart is a no-op. Kuma push monitors have no start event.
+func (k *KumaPinger) PingStart(handle string, params PingParams) (*InstanceConfig, error) {
+	return &InstanceConfig{}, nil
+}
+
+// PingLog is a no-op. Kuma push monitors have no log event.
+func (k *KumaPinger) PingLog(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return &InstanceConfig{}, nil
+}
+
+// PingSuccess pushes up status for the monitor with push token handle.
+func (k *KumaPinger) PingSuccess(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return k.push(handle, params, true, body)
+}
+
+// PingFail pushes down status for the monitor with push token handle.
+func (k *KumaPinger) PingFail(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return k.push(handle, params, false, body)
+}
+
+// PingExitCode pushes up status for a zero exitCode and down status otherwise
+// for the monitor with push token handle.
+func (k *KumaPinger) PingExitCode(handle string, params PingParams, exitCode int, body io.ReadSeeker) (*InstanceConfig, error) {
+	params.ExitCode = Some(exitCode)
+	return k.push(handle, params, exitCode == 0, body)
+}
+
+func (k *KumaPinger) push(handle string, params PingParams, up bool, body io.ReadSeeker) (*InstanceConfig, error) {
+	u, err := url.Parse(k.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path, err = url.JoinPath(u.Path, "api/push", handle)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if body != nil {
+		if out, err = io.ReadAll(body); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := outputTail(string(out), KumaMsgLimit)
+	status := "up"
+	if !up {
+		status = "down"
+		if code, ok := params.ExitCode.Get(); ok && len(msg) == 0 {
+			msg = fmt.Sprintf("exit code %d", code)
+		}
+	}
+
+	if len(msg) == 0 {
+		msg = "OK"
+	}
+
+	q := url.Values{}
+	q.Set("status", status)
+	q.Set("msg", msg)
+	if params.Duration > 0 {
+		q.Set("ping", strconv.FormatInt(params.Duration.Milliseconds(), 10))
+	}
+	u.RawQuery = q.Encode()
+
+	resp, err := k.Client.Request("GET", u.String(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var r struct {
+		OK  bool   `json:"ok"`
+		Msg string `json:"msg"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&r) == nil && !r.OK {
+		return nil, fmt.Errorf("push rejected: %s", r.Msg)
+	}
+
+	return &InstanceConfig{}, nil
+}
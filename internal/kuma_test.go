@@ -0,0 +1,97 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+const TestPushToken = "pushToken"
+
+// Tests if KumaPinger maps pings to push requests and skips start and log
+// pings.
+func TestKumaPinger(t *testing.T) {
+	t.Parallel()
+
+	var reqs []*url.URL
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("expected GET request, got %s", r.Method)
+		}
+
+		reqs = append(reqs, r.URL)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	k := &KumaPinger{BaseURL: ts.URL, Client: &APIClient{Client: ts.Client()}}
+	params := PingParams{RunId: TestRunId, Duration: 1500 * time.Millisecond}
+
+	if _, err := k.PingStart(TestPushToken, params); err != nil {
+		t.Fatalf("PingStart failed: %v", err)
+	}
+
+	if _, err := k.PingLog(TestPushToken, params, bytes.NewReader(TestPingBody)); err != nil {
+		t.Fatalf("PingLog failed: %v", err)
+	}
+
+	if _, err := k.PingExitCode(TestPushToken, params, 0, bytes.NewReader(TestPingBody)); err != nil {
+		t.Fatalf("PingExitCode failed: %v", err)
+	}
+
+	if _, err := k.PingExitCode(TestPushToken, params, 2, nil); err != nil {
+		t.Fatalf("PingExitCode failed: %v", err)
+	}
+
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 push requests, got %d", len(reqs))
+	}
+
+	expected := []url.Values{
+		{"status": {"up"}, "msg": {string(TestPingBody)}, "ping": {"1500"}},
+		{"status": {"down"}, "msg": {"exit code 2"}, "ping": {"1500"}},
+	}
+
+	for i, u := range reqs {
+		if u.Path != "/api/push/"+TestPushToken {
+			t.Errorf("expected push path, got %s", u.Path)
+		}
+
+		if got := u.Query().Encode(); got != expected[i].Encode() {
+			t.Errorf("expected query %s, got %s", expected[i].Encode(), got)
+		}
+	}
+}
+
+// Tests if long outputs are trimmed to their tail and rejected pushes are
+// reported.
+func TestKumaPingerMsgAndRejection(t *testing.T) {
+	t.Parallel()
+
+	var msg string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg = r.URL.Query().Get("msg")
+		w.Write([]byte(`{"ok":false,"msg":"Monitor not found or not active."}`))
+	}))
+	defer ts.Close()
+
+	k := &KumaPinger{BaseURL: ts.URL, Client: &APIClient{Client: ts.Client()}}
+	out := strings.Repeat("x", KumaMsgLimit) + "the end\n"
+
+	_, err := k.PingFail(TestPushToken, PingParams{}, strings.NewReader(out))
+	if err == nil || !strings.Contains(err.Error(), "Monitor not found") {
+		t.Errorf("expected rejected push error, got: %v", err)
+	}
+
+	if len(msg) != KumaMsgLimit || !strings.HasPrefix(msg, "...") || !strings.HasSuffix(msg, "the end") {
+		t.Errorf("expected msg trimmed to its tail, got %q", msg)
+	}
+}
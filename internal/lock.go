@@ -0,0 +1,147 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// LockPollInterval is the interval between lock attempts while waiting for a
+// lock held by another process.
+const LockPollInterval = 100 * time.Millisecond
+
+// errWouldBlock is returned by the platform specific tryLock implementations
+// when the lock is held by another open file description.
+var errWouldBlock = errors.New("would block")
+
+// LockOwner is the record a lock holder writes into the lock file.
+type LockOwner struct {
+	PID   int
+	RunId string
+}
+
+func (o LockOwner) String() string {
+	if len(o.RunId) == 0 {
+		return fmt.Sprintf("PID %d", o.PID)
+	}
+
+	return fmt.Sprintf("PID %d (run id %s)", o.PID, o.RunId)
+}
+
+// LockHeldError is the error returned by AcquireLock when the lock could not be
+// acquired within the wait duration.
+type LockHeldError struct {
+	Path  string
+	Owner Optional[LockOwner]
+}
+
+func (e *LockHeldError) Error() string {
+	if o, ok := e.Owner.Get(); ok {
+		return fmt.Sprintf("lock %s is held by %s", e.Path, o)
+	}
+
+	return fmt.Sprintf("lock %s is held by another process", e.Path)
+}
+
+// Lock is an exclusive, advisory lock on a file shared between processes.
+//
+// The holder records its PID and run id in the file and truncates it on
+// Release. A record found in the file at the time of acquisition means the
+// previous holder exited without releasing the lock.
+type Lock struct {
+	// Stale is the record left behind by a previous holder that did not
+	// release the lock.
+	Stale Optional[LockOwner]
+
+	f *os.File
+}
+
+// AcquireLock takes an exclusive lock on the file at path, creating it if
+// necessary, and records the calling process's PID and runId in it.
+//
+// If another process holds the lock, AcquireLock retries until wait elapses.
+// A zero wait makes a single attempt. Returns a *LockHeldError if the lock
+// could not be acquired in time.
+func AcquireLock(path, runId string, wait time.Duration) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		err = tryLock(f)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, errWouldBlock) {
+			f.Close()
+			return nil, fmt.Errorf("lock %s: %w", path, err)
+		}
+
+		if !time.Now().Before(deadline) {
+			owner := readLockOwner(f)
+			f.Close()
+			return nil, &LockHeldError{Path: path, Owner: owner}
+		}
+
+		time.Sleep(LockPollInterval)
+	}
+
+	l := &Lock{f: f, Stale: readLockOwner(f)}
+
+	err = l.record(LockOwner{PID: os.Getpid(), RunId: runId})
+	if err != nil {
+		l.Release()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+
+	return l, nil
+}
+
+func (l *Lock) record(o LockOwner) error {
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := l.f.WriteAt([]byte(fmt.Sprintf("%d %s\n", o.PID, o.RunId)), 0)
+
+	return err
+}
+
+// Release clears the holder record and releases the lock.
+func (l *Lock) Release() error {
+	l.f.Truncate(0)
+	// Closing the last file descriptor referring to the open file
+	// description releases the lock.
+	return l.f.Close()
+}
+
+func readLockOwner(f *os.File) Optional[LockOwner] {
+	b, err := io.ReadAll(io.NewSectionReader(f, 0, 128))
+	if err != nil {
+		return None[LockOwner]()
+	}
+
+	var o LockOwner
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return None[LockOwner]()
+	}
+
+	if _, err := fmt.Sscan(fields[0], &o.PID); err != nil {
+		return None[LockOwner]()
+	}
+
+	if len(fields) > 1 {
+		o.RunId = fields[1]
+	}
+
+	return Some(o)
+}
@@ -0,0 +1,15 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package internal
+
+import (
+	"errors"
+	"os"
+)
+
+func tryLock(f *os.File) error {
+	return errors.ErrUnsupported
+}
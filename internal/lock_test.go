@@ -0,0 +1,114 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package internal_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if a held lock is refused to a second holder along with the record of
+// the current owner, and can be acquired again once released.
+func TestLockContention(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "lock")
+
+	l, err := AcquireLock(path, TestRunId, 0)
+	if err != nil {
+		t.Fatalf("expected to acquire lock, got error: %v", err)
+	}
+
+	if l.Stale.IsDefined() {
+		t.Errorf("expected no stale record on a fresh lock file")
+	}
+
+	_, err = AcquireLock(path, "", 2*LockPollInterval)
+	var lhe *LockHeldError
+	if !errors.As(err, &lhe) {
+		t.Fatalf("expected LockHeldError, got: %v", err)
+	}
+
+	owner, ok := lhe.Owner.Get()
+	if !ok {
+		t.Fatalf("expected lock owner to be known")
+	}
+
+	if owner.PID != os.Getpid() || owner.RunId != TestRunId {
+		t.Errorf("expected owner PID %d run id %s, got %+v", os.Getpid(), TestRunId, owner)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+
+	l, err = AcquireLock(path, "", 0)
+	if err != nil {
+		t.Fatalf("expected to acquire released lock, got error: %v", err)
+	}
+
+	if l.Stale.IsDefined() {
+		t.Errorf("expected no stale record after a clean release")
+	}
+
+	l.Release()
+}
+
+// Tests if a waiting acquirer gets the lock once the holder releases it.
+func TestLockWait(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "lock")
+
+	l, err := AcquireLock(path, "", 0)
+	if err != nil {
+		t.Fatalf("expected to acquire lock, got error: %v", err)
+	}
+
+	go func() {
+		time.Sleep(2 * LockPollInterval)
+		l.Release()
+	}()
+
+	l2, err := AcquireLock(path, "", 10*time.Second)
+	if err != nil {
+		t.Fatalf("expected to acquire lock after wait, got error: %v", err)
+	}
+
+	l2.Release()
+}
+
+// Tests if a record left behind by a holder that didn't release the lock is
+// reported as stale.
+func TestLockStale(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "lock")
+	if err := os.WriteFile(path, []byte("12345 "+TestRunId+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := AcquireLock(path, "", 0)
+	if err != nil {
+		t.Fatalf("expected to acquire lock, got error: %v", err)
+	}
+
+	defer l.Release()
+
+	stale, ok := l.Stale.Get()
+	if !ok {
+		t.Fatalf("expected a stale record")
+	}
+
+	if stale.PID != 12345 || stale.RunId != TestRunId {
+		t.Errorf("unexpected stale record: %+v", stale)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	// Default Healthchecks Management API address.
+	DefaultManagementURL = "https://healthchecks.io"
+	// Management API version path prefix.
+	managementAPIPath = "api/v3"
+)
+
+// ErrCheckNotFound is returned when the Management API has no check matching
+// the requested handle.
+var ErrCheckNotFound = errors.New("check not found")
+
+// Check is a check as returned by the Management API.
+//
+// https://healthchecks.io/docs/api/
+type Check struct {
+	UUID         string `json:"uuid"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	Tags         string `json:"tags"`
+	Desc         string `json:"desc"`
+	Grace        int    `json:"grace"`   // seconds
+	Timeout      int    `json:"timeout"` // seconds. Zero if the check has a cron schedule
+	Schedule     string `json:"schedule"`
+	TZ           string `json:"tz"`
+	Status       string `json:"status"`
+	Started      bool   `json:"started"`
+	NPings       int    `json:"n_pings"`
+	LastPing     string `json:"last_ping"`
+	NextPing     string `json:"next_ping"`
+	ManualResume bool   `json:"manual_resume"`
+	Channels     string `json:"channels"`
+	PingURL      string `json:"ping_url"`
+	UpdateURL    string `json:"update_url"`
+}
+
+// CheckUUID returns the UUID of the check. Older API versions do not return
+// the uuid field, in which case the UUID is extracted from the update URL.
+func (c *Check) CheckUUID() string {
+	if len(c.UUID) > 0 || len(c.UpdateURL) == 0 {
+		return c.UUID
+	}
+
+	return path.Base(c.UpdateURL)
+}
+
+// CheckSpec is the set of check attributes to create or update a check with.
+// Nil fields are left out of the request, and keep their current or default
+// values.
+type CheckSpec struct {
+	Name     *string `json:"name,omitempty"`
+	Slug     *string `json:"slug,omitempty"`
+	Tags     *string `json:"tags,omitempty"`
+	Desc     *string `json:"desc,omitempty"`
+	Timeout  *int    `json:"timeout,omitempty"` // seconds
+	Grace    *int    `json:"grace,omitempty"`   // seconds
+	Schedule *string `json:"schedule,omitempty"`
+	TZ       *string `json:"tz,omitempty"`
+	Channels *string `json:"channels,omitempty"`
+
+	// Unique lists the fields used to find an existing check to update
+	// instead of creating a new one. Only used when creating checks.
+	Unique []string `json:"unique,omitempty"`
+}
+
+// IsEmpty reports whether the spec has no attributes set.
+func (s *CheckSpec) IsEmpty() bool {
+	return s.Name == nil && s.Slug == nil && s.Tags == nil && s.Desc == nil &&
+		s.Timeout == nil && s.Grace == nil && s.Schedule == nil && s.TZ == nil &&
+		s.Channels == nil
+}
+
+// ManagementClient is a client for the Healthchecks Management API. It uses
+// an APIClient for transport, retries and request headers.
+type ManagementClient struct {
+	// BaseURL is the base URL of Healthchecks instance.
+	BaseURL string
+
+	// APIKey is the project API key sent in the X-Api-Key header.
+	APIKey string
+
+	API *APIClient
+}
+
+// ListChecks returns the checks in the project, filtered by slug and tags if
+// they're non-empty.
+func (m *ManagementClient) ListChecks(slug string, tags []string) ([]Check, error) {
+	q := url.Values{}
+	if len(slug) > 0 {
+		q.Set("slug", slug)
+	}
+	for _, t := range tags {
+		q.Add("tag", t)
+	}
+
+	var resp struct {
+		Checks []Check `json:"checks"`
+	}
+
+	if err := m.do("GET", "checks/", q, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Checks, nil
+}
+
+// GetCheck returns the check with uuid.
+func (m *ManagementClient) GetCheck(uuid string) (*Check, error) {
+	c := &Check{}
+	if err := m.do("GET", "checks/"+url.PathEscape(uuid), nil, nil, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// CreateCheck creates a new check with spec. If spec.Unique is set and an
+// existing check matches it, the existing check is updated instead.
+func (m *ManagementClient) CreateCheck(spec *CheckSpec) (*Check, error) {
+	c := &Check{}
+	if err := m.do("POST", "checks/", nil, spec, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// UpdateCheck updates the check with uuid with the attributes in spec.
+func (m *ManagementClient) UpdateCheck(uuid string, spec *CheckSpec) (*Check, error) {
+	c := &Check{}
+	if err := m.do("POST", "checks/"+url.PathEscape(uuid), nil, spec, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// PauseCheck pauses monitoring of the check with uuid. The check stays paused
+// until it gets pinged or resumed.
+func (m *ManagementClient) PauseCheck(uuid string) (*Check, error) {
+	c := &Check{}
+	if err := m.do("POST", "checks/"+url.PathEscape(uuid)+"/pause", nil, nil, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ResumeCheck resumes monitoring of the paused check with uuid.
+func (m *ManagementClient) ResumeCheck(uuid string) (*Check, error) {
+	c := &Check{}
+	if err := m.do("POST", "checks/"+url.PathEscape(uuid)+"/resume", nil, nil, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (m *ManagementClient) do(method, endpoint string, query url.Values, in, out any) error {
+	u, err := url.Parse(m.BaseURL)
+	if err != nil {
+		return err
+	}
+
+	u.Path, err = url.JoinPath(u.Path, managementAPIPath, endpoint)
+	if err != nil {
+		return err
+	}
+
+	// url.JoinPath cleans the trailing slash some endpoints require.
+	if strings.HasSuffix(endpoint, "/") && !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	if len(query) > 0 {
+		u.RawQuery = query.Encode()
+	}
+
+	header := http.Header{"X-Api-Key": {m.APIKey}}
+
+	var body io.ReadSeeker
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(b)
+		header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.API.Request(method, u.String(), header, body)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return ErrCheckNotFound
+		}
+
+		if resp != nil {
+			// Management API describes errors in the body.
+			var e struct {
+				Error string `json:"error"`
+			}
+			if json.NewDecoder(resp.Body).Decode(&e) == nil && len(e.Error) > 0 {
+				return fmt.Errorf("%w: %s", err, e.Error)
+			}
+		}
+
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
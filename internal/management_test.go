@@ -0,0 +1,287 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+const (
+	TestAPIKey    = "test-api-key"
+	TestCheckUUID = "8116e449-d71c-4112-8f5d-a66f60902091"
+)
+
+// fakeManagementAPI is a minimal stand-in for Healthchecks Management API,
+// keeping checks in memory.
+type fakeManagementAPI struct {
+	mu     sync.Mutex
+	checks map[string]*Check
+	nextId int
+}
+
+func newFakeManagementAPI(t *testing.T) (*fakeManagementAPI, *ManagementClient) {
+	f := &fakeManagementAPI{checks: map[string]*Check{
+		TestCheckUUID: {UUID: TestCheckUUID, Name: "Backups", Slug: "backups", Timeout: 86400, Grace: 3600},
+	}}
+
+	ts := httptest.NewTLSServer(f)
+	t.Cleanup(ts.Close)
+
+	return f, &ManagementClient{
+		BaseURL: ts.URL,
+		APIKey:  TestAPIKey,
+		API:     &APIClient{Client: ts.Client()},
+	}
+}
+
+func (f *fakeManagementAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("X-Api-Key") != TestAPIKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "missing api key"})
+		return
+	}
+
+	id, found := strings.CutPrefix(r.URL.Path, "/api/v3/checks/")
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && len(id) == 0:
+		var list []Check
+		for _, c := range f.checks {
+			if slug := r.URL.Query().Get("slug"); len(slug) == 0 || c.Slug == slug {
+				list = append(list, *c)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string][]Check{"checks": list})
+
+	case r.Method == "GET":
+		c, ok := f.checks[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(c)
+
+	case r.Method == "POST" && (strings.HasSuffix(id, "/pause") || strings.HasSuffix(id, "/resume")):
+		id, action, _ := strings.Cut(id, "/")
+		c, ok := f.checks[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if action == "pause" {
+			c.Status = "paused"
+		} else if c.Status == "paused" {
+			c.Status = "new"
+		}
+		json.NewEncoder(w).Encode(c)
+
+	case r.Method == "POST":
+		var spec CheckSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "could not parse request body"})
+			return
+		}
+
+		var c *Check
+		status := http.StatusOK
+		if len(id) > 0 {
+			c = f.checks[id]
+		} else if slices.Contains(spec.Unique, "slug") && spec.Slug != nil {
+			for _, ec := range f.checks {
+				if ec.Slug == *spec.Slug {
+					c = ec
+				}
+			}
+		}
+
+		if c == nil && len(id) > 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if c == nil {
+			f.nextId++
+			c = &Check{UUID: strings.Repeat("0", 35) + string(rune('0'+f.nextId))}
+			f.checks[c.UUID] = c
+			status = http.StatusCreated
+		}
+
+		applySpec(c, &spec)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(c)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func applySpec(c *Check, s *CheckSpec) {
+	set := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	set(&c.Name, s.Name)
+	set(&c.Slug, s.Slug)
+	set(&c.Tags, s.Tags)
+	set(&c.Desc, s.Desc)
+	set(&c.Schedule, s.Schedule)
+	set(&c.TZ, s.TZ)
+	set(&c.Channels, s.Channels)
+
+	if s.Timeout != nil {
+		c.Timeout, c.Schedule = *s.Timeout, ""
+	}
+
+	if s.Grace != nil {
+		c.Grace = *s.Grace
+	}
+}
+
+// Tests if GetCheck and ListChecks return the checks and a missing check is
+// reported with ErrCheckNotFound.
+func TestManagementGetAndList(t *testing.T) {
+	t.Parallel()
+
+	_, m := newFakeManagementAPI(t)
+
+	c, err := m.GetCheck(TestCheckUUID)
+	if err != nil {
+		t.Fatalf("GetCheck failed: %v", err)
+	}
+
+	if c.Slug != "backups" || c.CheckUUID() != TestCheckUUID {
+		t.Errorf("unexpected check: %+v", c)
+	}
+
+	list, err := m.ListChecks("backups", nil)
+	if err != nil {
+		t.Fatalf("ListChecks failed: %v", err)
+	}
+
+	if len(list) != 1 || list[0].UUID != TestCheckUUID {
+		t.Errorf("expected to list check %s, got %+v", TestCheckUUID, list)
+	}
+
+	list, err = m.ListChecks("nonexistent", nil)
+	if err != nil || len(list) != 0 {
+		t.Errorf("expected an empty list, got %+v, err: %v", list, err)
+	}
+
+	_, err = m.GetCheck("00000000-0000-4000-8000-000000000000")
+	if !errors.Is(err, ErrCheckNotFound) {
+		t.Errorf("expected ErrCheckNotFound, got: %v", err)
+	}
+}
+
+// Tests if CreateCheck with unique slug updates an existing check and creates
+// a new one when there's no match.
+func TestManagementCreateUnique(t *testing.T) {
+	t.Parallel()
+
+	f, m := newFakeManagementAPI(t)
+
+	slug, tags := "backups", "prod"
+	c, err := m.CreateCheck(&CheckSpec{Slug: &slug, Tags: &tags, Unique: []string{"slug"}})
+	if err != nil {
+		t.Fatalf("CreateCheck failed: %v", err)
+	}
+
+	if c.UUID != TestCheckUUID || c.Tags != tags || c.Name != "Backups" {
+		t.Errorf("expected existing check to be updated, got %+v", c)
+	}
+
+	slug = "new-check"
+	c, err = m.CreateCheck(&CheckSpec{Slug: &slug, Unique: []string{"slug"}})
+	if err != nil {
+		t.Fatalf("CreateCheck failed: %v", err)
+	}
+
+	if c.UUID == TestCheckUUID || c.Slug != slug || len(f.checks) != 2 {
+		t.Errorf("expected a new check to be created, got %+v", c)
+	}
+}
+
+// Tests if UpdateCheck only changes the attributes set in the spec.
+func TestManagementUpdate(t *testing.T) {
+	t.Parallel()
+
+	_, m := newFakeManagementAPI(t)
+
+	timeout := 3600
+	c, err := m.UpdateCheck(TestCheckUUID, &CheckSpec{Timeout: &timeout})
+	if err != nil {
+		t.Fatalf("UpdateCheck failed: %v", err)
+	}
+
+	if c.Timeout != timeout || c.Grace != 3600 || c.Name != "Backups" {
+		t.Errorf("unexpected check after update: %+v", c)
+	}
+}
+
+// Tests if the API key is sent and error descriptions in responses are
+// surfaced.
+func TestManagementAuthError(t *testing.T) {
+	t.Parallel()
+
+	_, m := newFakeManagementAPI(t)
+	m.APIKey = "wrong"
+
+	_, err := m.GetCheck(TestCheckUUID)
+	if !errors.Is(err, ErrNonRetriable) {
+		t.Fatalf("expected ErrNonRetriable, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "missing api key") {
+		t.Errorf("expected error to include the description from the response body, got: %v", err)
+	}
+}
+
+// Tests if PauseCheck and ResumeCheck change the status of the check.
+func TestManagementPauseResume(t *testing.T) {
+	t.Parallel()
+
+	_, m := newFakeManagementAPI(t)
+
+	c, err := m.PauseCheck(TestCheckUUID)
+	if err != nil {
+		t.Fatalf("PauseCheck failed: %v", err)
+	}
+
+	if c.Status != "paused" {
+		t.Errorf("expected check to be paused, got status %q", c.Status)
+	}
+
+	c, err = m.ResumeCheck(TestCheckUUID)
+	if err != nil {
+		t.Fatalf("ResumeCheck failed: %v", err)
+	}
+
+	if c.Status == "paused" {
+		t.Errorf("expected check to be resumed, got status %q", c.Status)
+	}
+
+	_, err = m.PauseCheck("00000000-0000-4000-8000-000000000000")
+	if !errors.Is(err, ErrCheckNotFound) {
+		t.Errorf("expected ErrCheckNotFound, got: %v", err)
+	}
+}
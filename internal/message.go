@@ -0,0 +1,27 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// outputTail trims the surrounding whitespace of output and shortens it to
+// limit bytes for backends with short message fields, keeping its tail where
+// errors usually are.
+func outputTail(output string, limit int) string {
+	msg := strings.TrimSpace(output)
+	if len(msg) <= limit {
+		return msg
+	}
+
+	const ellipsis = "..."
+	msg = msg[len(msg)-limit+len(ellipsis):]
+	// Don't start in the middle of a multibyte character.
+	for len(msg) > 0 && !utf8.RuneStart(msg[0]) {
+		msg = msg[1:]
+	}
+
+	return ellipsis + msg
+}
@@ -0,0 +1,222 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"cmp"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// Buckets of the run duration histogram in seconds, from a second to
+	// a day.
+	RunDurationBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 3 * 3600, 6 * 3600, 12 * 3600, 24 * 3600}
+	// Buckets of the ping duration histogram in seconds.
+	PingDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+)
+
+// Histogram is a cumulative histogram of observed values in Prometheus
+// fashion. It's not safe for concurrent use.
+type Histogram struct {
+	Buckets []float64 // Upper bounds in increasing order, without +Inf
+
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe adds v to the histogram.
+func (h *Histogram) Observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(h.Buckets))
+	}
+
+	for i, le := range h.Buckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+
+	h.count++
+	h.sum += v
+}
+
+// Samples returns the _bucket, _sum and _count samples of the histogram with
+// labels.
+func (h *Histogram) Samples(labels []PromLabel) []PromSample {
+	var samples []PromSample
+
+	bucket := func(le string, n uint64) {
+		l := append(slices.Clip(labels), PromLabel{Name: "le", Value: le})
+		samples = append(samples, PromSample{Suffix: "_bucket", Labels: l, Value: float64(n)})
+	}
+
+	for i, le := range h.Buckets {
+		var n uint64
+		if h.counts != nil {
+			n = h.counts[i]
+		}
+		bucket(strconv.FormatFloat(le, 'f', -1, 64), n)
+	}
+	bucket("+Inf", h.count)
+
+	return append(samples,
+		PromSample{Suffix: "_sum", Labels: labels, Value: h.sum},
+		PromSample{Suffix: "_count", Labels: labels, Value: float64(h.count)},
+	)
+}
+
+// Metrics collects the metrics of runs and pings of a runitor process and
+// serves them in Prometheus text exposition format.
+//
+// Pings are measured by wrapping the Pinger with Pinger, and the HTTP status
+// of each request try by setting OnAttempt as the hook of the APIClient
+// tagging the pings.
+type Metrics struct {
+	// Labels are added to every sample, like the check and the job.
+	Labels []PromLabel
+
+	// Runs accumulates the results of the runs.
+	Runs RunStats
+
+	mu            sync.Mutex
+	runDurations  Histogram
+	pingDurations map[string]*Histogram // by ping type
+	pingFailures  map[string]uint64     // by ping type
+	requests      map[[2]string]uint64  // by ping type and HTTP status
+}
+
+// RecordRun adds the result r of a finished run to the metrics. Skipped runs
+// aren't recorded.
+func (m *Metrics) RecordRun(r RunResult) {
+	m.Runs.Record(r)
+	if r.Skipped {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.runDurations.Buckets == nil {
+		m.runDurations.Buckets = RunDurationBuckets
+	}
+	m.runDurations.Observe(r.Duration().Seconds())
+}
+
+// OnAttempt counts a request try of a ping by its HTTP status, or "error" if
+// no response was received. Tries outside of pings, like Management API
+// requests, aren't counted.
+func (m *Metrics) OnAttempt(a Attempt) {
+	if len(a.Ping) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code := "error"
+	if a.StatusCode > 0 {
+		code = strconv.Itoa(a.StatusCode)
+	}
+
+	if m.requests == nil {
+		m.requests = make(map[[2]string]uint64)
+	}
+	m.requests[[2]string{a.Ping, code}]++
+}
+
+// measure sends a ping of type pt with ping and records its duration and
+// failure.
+func (m *Metrics) measure(pt string, params PingParams, ping PingFunc) (*InstanceConfig, error) {
+	start := time.Now()
+	icfg, err := ping()
+	d := time.Since(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.pingDurations == nil {
+		m.pingDurations = make(map[string]*Histogram)
+		m.pingFailures = make(map[string]uint64)
+	}
+
+	h, ok := m.pingDurations[pt]
+	if !ok {
+		h = &Histogram{Buckets: PingDurationBuckets}
+		m.pingDurations[pt] = h
+	}
+	h.Observe(d.Seconds())
+
+	if err != nil {
+		m.pingFailures[pt]++
+	}
+
+	return icfg, err
+}
+
+// Collect returns the metrics of the runs and pings so far.
+func (m *Metrics) Collect() []PromMetric {
+	metrics := m.Runs.Metrics(m.Labels, true)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.runDurations.Buckets != nil {
+		metrics = append(metrics, PromMetric{
+			Name:    "runitor_run_duration_seconds",
+			Help:    "Duration of the runs of the command in seconds.",
+			Type:    "histogram",
+			Samples: m.runDurations.Samples(m.Labels),
+		})
+	}
+
+	withType := func(pt string) []PromLabel {
+		return append(slices.Clip(m.Labels), PromLabel{Name: "type", Value: pt})
+	}
+
+	pingDurations := PromMetric{
+		Name: "runitor_ping_duration_seconds",
+		Help: "Duration of pings in seconds, including retries.",
+		Type: "histogram",
+	}
+	pingFailures := PromMetric{
+		Name: "runitor_ping_failures_total",
+		Help: "Number of pings that couldn't be delivered after retries.",
+		Type: "counter",
+	}
+	for _, pt := range slices.Sorted(maps.Keys(m.pingDurations)) {
+		pingDurations.Samples = append(pingDurations.Samples, m.pingDurations[pt].Samples(withType(pt))...)
+		pingFailures.Samples = append(pingFailures.Samples, PromSample{Labels: withType(pt), Value: float64(m.pingFailures[pt])})
+	}
+
+	requests := PromMetric{
+		Name: "runitor_ping_requests_total",
+		Help: "Number of ping request tries by HTTP status code, or error if no response was received.",
+		Type: "counter",
+	}
+	keys := slices.SortedFunc(maps.Keys(m.requests), func(a, b [2]string) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+	for _, k := range keys {
+		l := append(withType(k[0]), PromLabel{Name: "code", Value: k[1]})
+		requests.Samples = append(requests.Samples, PromSample{Labels: l, Value: float64(m.requests[k])})
+	}
+
+	return append(metrics, pingDurations, pingFailures, requests)
+}
+
+// ServeHTTP serves the metrics in Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", PromTextContentType)
+	WritePromText(w, m.Collect())
+}
+
+// Pinger returns a Pinger sending pings with p and measuring them.
+func (m *Metrics) Pinger(p Pinger) Pinger {
+	return WrapPinger(p, m.measure)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if Metrics measures pings by type and HTTP status, records runs, and
+// serves them in text exposition format.
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	var reqs atomic.Int32
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail every other request.
+		if reqs.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	m := &Metrics{Labels: []PromLabel{{Name: "job", Value: "backup"}}}
+	c := &APIClient{BaseURL: ts.URL, Client: ts.Client(), Retries: 1, Backoff: time.Millisecond, OnAttempt: m.OnAttempt}
+	p := m.Pinger(c.TagPings(c))
+
+	if _, err := p.PingStart(TestHandle, TestPingParamsNone); err != nil {
+		t.Fatalf("PingStart failed: %v", err)
+	}
+
+	c.Retries = 0
+	if _, err := p.PingExitCode(TestHandle, TestPingParamsNone, 1, nil); err == nil {
+		t.Fatal("expected PingExitCode to fail")
+	}
+
+	started := time.Now()
+	m.RecordRun(RunResult{ExitCode: 1, Started: started, Ended: started.Add(2 * time.Second)})
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, _ := io.ReadAll(resp.Body)
+	for _, line := range []string{
+		`runitor_ping_requests_total{job="backup",type="start",code="503"} 1`,
+		`runitor_ping_requests_total{job="backup",type="start",code="200"} 1`,
+		`runitor_ping_requests_total{job="backup",type="exit-code",code="503"} 1`,
+		`runitor_ping_failures_total{job="backup",type="start"} 0`,
+		`runitor_ping_failures_total{job="backup",type="exit-code"} 1`,
+		`runitor_ping_duration_seconds_count{job="backup",type="start"} 1`,
+		`runitor_run_duration_seconds_bucket{job="backup",le="1"} 0`,
+		`runitor_run_duration_seconds_bucket{job="backup",le="5"} 1`,
+		`runitor_run_duration_seconds_bucket{job="backup",le="+Inf"} 1`,
+		`runitor_run_duration_seconds_sum{job="backup"} 2`,
+		`runitor_last_run_exit_code{job="backup"} 1`,
+		`runitor_runs_total{job="backup"} 1`,
+	} {
+		if !strings.Contains(string(b), line+"\n") {
+			t.Errorf("expected metrics to contain %q, got:\n%s", line, b)
+		}
+	}
+}
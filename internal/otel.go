@@ -0,0 +1,361 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLP span kinds and status codes.
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusCodeError  = 2
+)
+
+// Tracer records spans and exports them to an OpenTelemetry collector with
+// OTLP/HTTP in JSON encoding.
+//
+// Methods of a nil *Tracer and a nil *Span are no-ops, so code paths can be
+// traced without checking if tracing is enabled.
+//
+// https://opentelemetry.io/docs/specs/otlp/#otlphttp
+type Tracer struct {
+	// Endpoint is the URL spans are posted to, like
+	// http://localhost:4318/v1/traces.
+	Endpoint string
+
+	// Header holds additional headers for export requests, like the ones
+	// for authentication.
+	Header http.Header
+
+	// ServiceName and ServiceVersion describe the traced service.
+	ServiceName, ServiceVersion string
+
+	// Client is used to export spans. It should not have an OnAttempt hook
+	// tracing the exports themselves.
+	Client *APIClient
+
+	mu      sync.Mutex
+	spans   []*Span // ended, not exported yet
+	current *Span   // span of the ping in flight, receiving its request tries
+}
+
+// Span is a timed operation in a trace.
+type Span struct {
+	t *Tracer
+
+	TraceID, SpanID, ParentSpanID string
+	Name                          string
+	Kind                          int
+	Start, End                    time.Time
+	Attributes                    map[string]any
+	Error                         string // Error status message, if the operation failed
+	failed                        bool
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Start starts a span called name as a child of parent, or as the root of a
+// new trace if parent is nil.
+func (t *Tracer) Start(name string, parent *Span) *Span {
+	if t == nil {
+		return nil
+	}
+
+	s := &Span{
+		t:          t,
+		SpanID:     randomHex(8),
+		Name:       name,
+		Kind:       spanKindInternal,
+		Start:      time.Now(),
+		Attributes: make(map[string]any),
+	}
+
+	if parent != nil {
+		s.TraceID, s.ParentSpanID = parent.TraceID, parent.SpanID
+	} else {
+		s.TraceID = randomHex(16)
+	}
+
+	return s
+}
+
+// SetAttr sets the attribute key of the span to v. Strings, bools, integers
+// and floats are supported.
+func (s *Span) SetAttr(key string, v any) {
+	if s == nil {
+		return
+	}
+
+	s.Attributes[key] = v
+}
+
+// SetError marks the span as failed with msg.
+func (s *Span) SetError(msg string) {
+	if s == nil {
+		return
+	}
+
+	s.failed, s.Error = true, msg
+}
+
+// Finish ends the span and queues it for export.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+
+	if s.End.IsZero() {
+		s.End = time.Now()
+	}
+
+	s.t.mu.Lock()
+	s.t.spans = append(s.t.spans, s)
+	s.t.mu.Unlock()
+}
+
+// Traceparent returns the W3C Trace Context traceparent header value
+// referring to the span, or an empty string for a nil span.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+
+	return "00-" + s.TraceID + "-" + s.SpanID + "-01"
+}
+
+// OnAttempt records a request try of a ping as a child span of the ping's
+// span.
+func (t *Tracer) OnAttempt(a Attempt) {
+	if len(a.Ping) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	parent := t.current
+	t.mu.Unlock()
+
+	if parent == nil {
+		return
+	}
+
+	s := t.Start("HTTP "+a.Method, parent)
+	s.Kind = spanKindClient
+	s.End = time.Now()
+	s.Start = s.End.Add(-a.Duration)
+	s.SetAttr("http.request.method", a.Method)
+	if u, err := url.Parse(a.URL); err == nil {
+		// Only the host. The path may have the ping key.
+		s.SetAttr("server.address", u.Hostname())
+	}
+	if a.StatusCode > 0 {
+		s.SetAttr("http.response.status_code", a.StatusCode)
+	}
+	if a.Err != nil {
+		s.SetError(a.Err.Error())
+	} else if a.StatusCode >= 400 {
+		s.SetError(strconv.Itoa(a.StatusCode) + " " + http.StatusText(a.StatusCode))
+	}
+	s.Finish()
+}
+
+// Flush exports the ended spans.
+func (t *Tracer) Flush() error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(t.request(spans))
+	if err != nil {
+		return err
+	}
+
+	header := t.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/json")
+
+	resp, err := t.Client.Request("POST", t.Endpoint, header, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	return nil
+}
+
+// OTLP JSON encoding of ExportTraceServiceRequest.
+type (
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"` // int64 as string
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+
+	otlpScopeSpans struct {
+		Scope struct {
+			Name    string `json:"name"`
+			Version string `json:"version,omitempty"`
+		} `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+)
+
+func otlpAttr(key string, v any) otlpKeyValue {
+	var val otlpValue
+	switch v := v.(type) {
+	case string:
+		val.StringValue = &v
+	case bool:
+		val.BoolValue = &v
+	case int:
+		s := strconv.Itoa(v)
+		val.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		val.IntValue = &s
+	case uint:
+		s := strconv.FormatUint(uint64(v), 10)
+		val.IntValue = &s
+	case float64:
+		val.DoubleValue = &v
+	default:
+		s := ""
+		if b, err := json.Marshal(v); err == nil {
+			s = string(b)
+		}
+		val.StringValue = &s
+	}
+
+	return otlpKeyValue{Key: key, Value: val}
+}
+
+func (t *Tracer) request(spans []*Span) *otlpRequest {
+	var rs otlpResourceSpans
+	rs.Resource.Attributes = []otlpKeyValue{otlpAttr("service.name", t.ServiceName)}
+	if len(t.ServiceVersion) > 0 {
+		rs.Resource.Attributes = append(rs.Resource.Attributes, otlpAttr("service.version", t.ServiceVersion))
+	}
+
+	var ss otlpScopeSpans
+	ss.Scope.Name, ss.Scope.Version = "runitor", t.ServiceVersion
+
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentSpanID,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+
+		for _, k := range slices.Sorted(maps.Keys(s.Attributes)) {
+			span.Attributes = append(span.Attributes, otlpAttr(k, s.Attributes[k]))
+		}
+
+		if s.failed {
+			span.Status = otlpStatus{Code: statusCodeError, Message: s.Error}
+		}
+
+		ss.Spans = append(ss.Spans, span)
+	}
+
+	rs.ScopeSpans = []otlpScopeSpans{ss}
+
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+// Pinger returns a Pinger sending pings with p, each traced as a child span
+// of parent. Returns p as is for a nil *Tracer.
+//
+// Request tries are recorded under the span of the ping in flight, so the
+// pings must be sent one at a time, like through APIClient.TagPings.
+func (t *Tracer) Pinger(p Pinger, parent *Span) Pinger {
+	if t == nil {
+		return p
+	}
+
+	return WrapPinger(p, func(pt string, params PingParams, ping PingFunc) (*InstanceConfig, error) {
+		s := t.Start("ping "+pt, parent)
+		s.SetAttr("runitor.ping.type", pt)
+
+		t.mu.Lock()
+		t.current = s
+		t.mu.Unlock()
+
+		icfg, err := ping()
+
+		t.mu.Lock()
+		t.current = nil
+		t.mu.Unlock()
+
+		if err != nil {
+			s.SetError(err.Error())
+		}
+		s.Finish()
+
+		return icfg, err
+	})
+}
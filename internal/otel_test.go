@@ -0,0 +1,161 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+type testSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+			IntValue    string `json:"intValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code int `json:"code"`
+	} `json:"status"`
+}
+
+func (s *testSpan) attr(key string) string {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value.StringValue + a.Value.IntValue
+		}
+	}
+
+	return ""
+}
+
+// Tests if Tracer exports ping spans with a child span per request try as
+// OTLP JSON, and if Traceparent refers to the span.
+func TestTracer(t *testing.T) {
+	t.Parallel()
+
+	var reqs atomic.Int32
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first request.
+		if reqs.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	var export struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []testSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	var header http.Header
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+			t.Errorf("decoding export: %v", err)
+		}
+	}))
+	defer collector.Close()
+
+	tr := &Tracer{
+		Endpoint:    collector.URL + "/v1/traces",
+		Header:      http.Header{"Authorization": {"Bearer secret"}},
+		ServiceName: "runitor",
+		Client:      &APIClient{Client: collector.Client()},
+	}
+	c := &APIClient{BaseURL: ts.URL, Client: ts.Client(), Retries: 1, Backoff: time.Millisecond, OnAttempt: tr.OnAttempt}
+
+	run := tr.Start("run", nil)
+	run.SetAttr("runitor.run_id", TestRunId)
+
+	if tp := run.Traceparent(); len(tp) != 55 || tp != "00-"+run.TraceID+"-"+run.SpanID+"-01" {
+		t.Errorf("unexpected traceparent %q", tp)
+	}
+
+	if _, err := tr.Pinger(c.TagPings(c), run).PingStart(TestHandle, TestPingParamsNone); err != nil {
+		t.Fatalf("PingStart failed: %v", err)
+	}
+	run.Finish()
+
+	if err := tr.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if got := header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("expected Authorization header to be passed, got %q", got)
+	}
+
+	if len(export.ResourceSpans) != 1 || len(export.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected export structure: %+v", export)
+	}
+
+	spans := make(map[string][]testSpan)
+	for _, s := range export.ResourceSpans[0].ScopeSpans[0].Spans {
+		if s.TraceID != run.TraceID {
+			t.Errorf("span %q isn't in the run's trace", s.Name)
+		}
+		spans[s.Name] = append(spans[s.Name], s)
+	}
+
+	if len(spans["run"]) != 1 || spans["run"][0].attr("runitor.run_id") != TestRunId {
+		t.Errorf("expected a run span with the run id, got %+v", spans["run"])
+	}
+
+	ping := spans["ping start"]
+	if len(ping) != 1 || ping[0].ParentSpanID != run.SpanID {
+		t.Fatalf("expected a ping span under the run span, got %+v", ping)
+	}
+
+	tries := spans["HTTP POST"]
+	if len(tries) != 2 {
+		t.Fatalf("expected 2 request try spans, got %+v", tries)
+	}
+	for i, code := range []string{"503", "200"} {
+		if tries[i].ParentSpanID != ping[0].SpanID {
+			t.Errorf("expected try %d under the ping span", i+1)
+		}
+		if got := tries[i].attr("http.response.status_code"); got != code {
+			t.Errorf("expected try %d status %s, got %q", i+1, code, got)
+		}
+	}
+	if tries[0].Status.Code != 2 || tries[1].Status.Code != 0 {
+		t.Errorf("expected only the first try to have error status, got %d and %d", tries[0].Status.Code, tries[1].Status.Code)
+	}
+
+	// Nothing left to export.
+	if err := tr.Flush(); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+}
+
+// Tests if a nil Tracer and its spans are no-ops.
+func TestTracerNil(t *testing.T) {
+	t.Parallel()
+
+	var tr *Tracer
+	s := tr.Start("run", nil)
+	s.SetAttr("key", "value")
+	s.SetError("failed")
+	s.Finish()
+
+	if s != nil || s.Traceparent() != "" {
+		t.Errorf("expected nil span, got %+v", s)
+	}
+
+	if err := tr.Flush(); err != nil {
+		t.Errorf("Flush failed: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"log/slog"
+	"time"
+)
+
+// PingLogger logs pings and their request tries with structured fields, so
+// undelivered pings can be alerted on.
+//
+// Pings are logged by wrapping the Pinger with Pinger, and request tries by
+// setting OnAttempt as the hook of the APIClient tagging the pings. Delivered
+// pings and successful tries are logged at debug level, failed tries at warn
+// level, and pings that couldn't be delivered at error level.
+type PingLogger struct {
+	// Logger to log to. If nil, slog's default logger.
+	Logger *slog.Logger
+}
+
+// with returns the logger with the fields of a ping of type pt with runId.
+func (l *PingLogger) with(pt, runId string) *slog.Logger {
+	logger := l.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	logger = logger.With("ping", pt)
+	if len(runId) > 0 {
+		logger = logger.With("run_id", runId)
+	}
+
+	return logger
+}
+
+// OnAttempt logs a request try of a ping with its try number, HTTP status, and
+// latency.
+func (l *PingLogger) OnAttempt(a Attempt) {
+	if len(a.Ping) == 0 {
+		return
+	}
+
+	logger := l.with(a.Ping, a.RunId)
+	attrs := []any{"attempt", a.Try, "latency", a.Duration}
+	if a.StatusCode > 0 {
+		attrs = append(attrs, "status", a.StatusCode)
+	}
+
+	if a.Err == nil && a.StatusCode >= 200 && a.StatusCode <= 299 {
+		logger.Debug("ping request", attrs...)
+		return
+	}
+
+	if a.Err != nil {
+		attrs = append(attrs, "error", a.Err)
+	}
+	logger.Warn("ping request failed", attrs...)
+}
+
+// Pinger returns a Pinger sending pings with p and logging them.
+func (l *PingLogger) Pinger(p Pinger) Pinger {
+	return WrapPinger(p, func(pt string, params PingParams, ping PingFunc) (*InstanceConfig, error) {
+		logger := l.with(pt, params.RunId)
+
+		start := time.Now()
+		icfg, err := ping()
+		d := time.Since(start)
+
+		if err != nil {
+			logger.Error("ping failed", "duration", d, "error", err)
+		} else {
+			logger.Debug("ping sent", "duration", d)
+		}
+
+		return icfg, err
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if PingLogger logs request tries and undelivered pings with the ping
+// type, run id, try number and HTTP status.
+func TestPingLogger(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	l := &PingLogger{Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+	c := &APIClient{BaseURL: ts.URL, Client: ts.Client(), Retries: 1, Backoff: time.Millisecond, OnAttempt: l.OnAttempt}
+
+	if _, err := l.Pinger(c.TagPings(c)).PingExitCode(TestHandle, TestPingParamsWithRID, 1, nil); err == nil {
+		t.Fatal("expected PingExitCode to fail")
+	}
+
+	type record struct {
+		Level   string
+		Msg     string
+		Ping    string
+		RunId   string `json:"run_id"`
+		Attempt uint
+		Status  int
+		Error   string
+	}
+
+	var got []record
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 log records, got %+v", got)
+	}
+
+	for i, r := range got {
+		if r.Ping != "exit-code" || r.RunId != TestRunId {
+			t.Errorf("expected record %d to have ping type and run id, got %+v", i, r)
+		}
+	}
+
+	for i := range 2 {
+		if r := got[i]; r.Level != "WARN" || r.Attempt != uint(i+1) || r.Status != http.StatusServiceUnavailable {
+			t.Errorf("expected try %d to be logged as failed with its status, got %+v", i+1, r)
+		}
+	}
+
+	if r := got[2]; r.Level != "ERROR" || r.Msg != "ping failed" || len(r.Error) == 0 {
+		t.Errorf("expected undelivered ping to be logged as error, got %+v", r)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import "io"
+
+// PingFunc sends a ping.
+type PingFunc func() (*InstanceConfig, error)
+
+// WrapPinger returns a Pinger sending pings with p through wrap. wrap gets
+// the type of each ping, like "start" or "exit-code", its parameters, and
+// the function sending it, which it must call.
+func WrapPinger(p Pinger, wrap func(pt string, params PingParams, ping PingFunc) (*InstanceConfig, error)) Pinger {
+	return &wrappedPinger{p: p, wrap: wrap}
+}
+
+type wrappedPinger struct {
+	p    Pinger
+	wrap func(string, PingParams, PingFunc) (*InstanceConfig, error)
+}
+
+func (w *wrappedPinger) PingStart(handle string, params PingParams) (*InstanceConfig, error) {
+	return w.wrap("start", params, func() (*InstanceConfig, error) {
+		return w.p.PingStart(handle, params)
+	})
+}
+
+func (w *wrappedPinger) PingLog(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return w.wrap("log", params, func() (*InstanceConfig, error) {
+		return w.p.PingLog(handle, params, body)
+	})
+}
+
+func (w *wrappedPinger) PingSuccess(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return w.wrap("success", params, func() (*InstanceConfig, error) {
+		return w.p.PingSuccess(handle, params, body)
+	})
+}
+
+func (w *wrappedPinger) PingFail(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return w.wrap("fail", params, func() (*InstanceConfig, error) {
+		return w.p.PingFail(handle, params, body)
+	})
+}
+
+func (w *wrappedPinger) PingExitCode(handle string, params PingParams, exitCode int, body io.ReadSeeker) (*InstanceConfig, error) {
+	return w.wrap("exit-code", params, func() (*InstanceConfig, error) {
+		return w.p.PingExitCode(handle, params, exitCode, body)
+	})
+}
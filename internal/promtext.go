@@ -0,0 +1,90 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PromTextContentType is the content type of Prometheus text exposition
+// format.
+const PromTextContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PromLabel is a Prometheus label name and value pair.
+type PromLabel struct {
+	Name, Value string
+}
+
+// PromSample is a sample of a metric with its labels.
+type PromSample struct {
+	Suffix string // Appended to the metric name, like _bucket of histograms
+	Labels []PromLabel
+	Value  float64
+}
+
+// PromMetric is a metric family to be written in Prometheus text exposition
+// format.
+type PromMetric struct {
+	Name    string
+	Help    string
+	Type    string // counter, gauge, histogram or untyped
+	Samples []PromSample
+}
+
+var promLabelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var promHelpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// WritePromText writes metrics to w in Prometheus text exposition format
+// version 0.0.4. Metrics without samples are skipped.
+//
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+func WritePromText(w io.Writer, metrics []PromMetric) error {
+	bw := bufio.NewWriter(w)
+
+	for _, m := range metrics {
+		if len(m.Samples) == 0 {
+			continue
+		}
+
+		if len(m.Help) > 0 {
+			bw.WriteString("# HELP " + m.Name + " " + promHelpEscaper.Replace(m.Help) + "\n")
+		}
+		if len(m.Type) > 0 {
+			bw.WriteString("# TYPE " + m.Name + " " + m.Type + "\n")
+		}
+
+		for _, s := range m.Samples {
+			bw.WriteString(m.Name + s.Suffix)
+			if len(s.Labels) > 0 {
+				bw.WriteByte('{')
+				for i, l := range s.Labels {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					bw.WriteString(l.Name + `="` + promLabelValueEscaper.Replace(l.Value) + `"`)
+				}
+				bw.WriteByte('}')
+			}
+			bw.WriteString(" " + formatPromValue(s.Value) + "\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+func formatPromValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
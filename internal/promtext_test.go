@@ -0,0 +1,47 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if metrics are written in text exposition format with label values
+// escaped and empty metrics skipped.
+func TestWritePromText(t *testing.T) {
+	t.Parallel()
+
+	metrics := []PromMetric{
+		{
+			Name: "test_total",
+			Help: "Help with \\ and\nnewline.",
+			Type: "counter",
+			Samples: []PromSample{
+				{Value: 3},
+				{Labels: []PromLabel{{"a", `q"b\` + "\n"}, {"b", "x"}}, Value: 0.25},
+			},
+		},
+		{Name: "test_empty", Type: "gauge"},
+		{Name: "test_inf", Samples: []PromSample{{Value: math.Inf(1)}}},
+	}
+
+	exp := `# HELP test_total Help with \\ and\nnewline.
+# TYPE test_total counter
+test_total 3
+test_total{a="q\"b\\\n",b="x"} 0.25
+test_inf +Inf
+`
+
+	var b strings.Builder
+	if err := WritePromText(&b, metrics); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.String() != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, b.String())
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PushgatewayPinger implements Pinger by pushing the result of each run to a
+// Prometheus Pushgateway. The check handle is used as the job label.
+//
+// Metrics are pushed with POST, so a failed run keeps the last success
+// timestamp pushed by a previous process. runitor_runs_total counts the runs
+// of this process, so it keeps growing only in periodic mode and is reset to
+// 1 by each one-shot run.
+//
+// Start and log pings have no metrics, so they aren't pushed.
+type PushgatewayPinger struct {
+	// BaseURL is the address of the Pushgateway.
+	BaseURL string
+
+	// Grouping is the labels grouping the metrics in addition to job, like
+	// instance.
+	Grouping []PromLabel
+
+	// Client sends the pushes.
+	Client *APIClient
+
+	stats RunStats
+}
+
+// PingStart is a no-op.
+func (p *PushgatewayPinger) PingStart(handle string, params PingParams) (*InstanceConfig, error) {
+	return &InstanceConfig{}, nil
+}
+
+// PingLog is a no-op.
+func (p *PushgatewayPinger) PingLog(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return &InstanceConfig{}, nil
+}
+
+// PingSuccess pushes the metrics of a successful run for job handle.
+func (p *PushgatewayPinger) PingSuccess(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	code, _ := params.ExitCode.Get()
+	return p.push(handle, params, code, true)
+}
+
+// PingFail pushes the metrics of a failed run for job handle.
+func (p *PushgatewayPinger) PingFail(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	code, ok := params.ExitCode.Get()
+	if !ok || code == 0 {
+		code = 1
+	}
+
+	return p.push(handle, params, code, false)
+}
+
+// PingExitCode pushes the metrics of a run with exitCode for job handle. Zero
+// exitCode is a successful run.
+func (p *PushgatewayPinger) PingExitCode(handle string, params PingParams, exitCode int, body io.ReadSeeker) (*InstanceConfig, error) {
+	return p.push(handle, params, exitCode, exitCode == 0)
+}
+
+func (p *PushgatewayPinger) push(job string, params PingParams, exitCode int, success bool) (*InstanceConfig, error) {
+	u, err := p.groupURL(job)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	p.stats.Record(RunResult{
+		ExitCode: exitCode,
+		Success:  success,
+		Started:  now.Add(-params.Duration),
+		Ended:    now,
+	})
+
+	var b bytes.Buffer
+	if err := WritePromText(&b, p.stats.Metrics(nil, false)); err != nil {
+		return nil, err
+	}
+
+	header := http.Header{"Content-Type": {PromTextContentType}}
+	resp, err := p.Client.Request("POST", u, header, bytes.NewReader(b.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	return &InstanceConfig{}, nil
+}
+
+// groupURL returns the URL of the metrics group of job and p.Grouping labels.
+// Label values that cannot be path segments are base64 encoded.
+func (p *PushgatewayPinger) groupURL(job string) (string, error) {
+	u, err := url.Parse(p.BaseURL)
+	if err != nil {
+		return "", err
+	}
+
+	segments := []string{"metrics"}
+	for _, l := range append([]PromLabel{{"job", job}}, p.Grouping...) {
+		if len(l.Value) == 0 || strings.Contains(l.Value, "/") {
+			enc := base64.RawURLEncoding.EncodeToString([]byte(l.Value))
+			if len(enc) == 0 {
+				enc = "="
+			}
+			segments = append(segments, l.Name+"@base64", enc)
+		} else {
+			segments = append(segments, l.Name, l.Value)
+		}
+	}
+
+	u = u.JoinPath(segments...)
+
+	return u.String(), nil
+}
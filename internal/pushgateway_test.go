@@ -0,0 +1,83 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if PushgatewayPinger pushes run metrics to the job's group and leaves
+// the last success timestamp out until a run succeeds.
+func TestPushgatewayPinger(t *testing.T) {
+	t.Parallel()
+
+	type push struct{ path, body string }
+	var pushes []push
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("expected POST request, got %s", r.Method)
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != PromTextContentType {
+			t.Errorf("unexpected content-type %s", ct)
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		pushes = append(pushes, push{r.URL.EscapedPath(), string(b)})
+	}))
+	defer ts.Close()
+
+	p := &PushgatewayPinger{
+		BaseURL:  ts.URL,
+		Grouping: []PromLabel{{"instance", "db1"}, {"path", "/var/backups"}},
+		Client:   &APIClient{Client: ts.Client()},
+	}
+
+	if _, err := p.PingStart("backups", PingParams{}); err != nil {
+		t.Fatalf("PingStart failed: %v", err)
+	}
+
+	params := PingParams{Duration: 1500 * time.Millisecond}
+	if _, err := p.PingExitCode("backups", params, 3, nil); err != nil {
+		t.Fatalf("PingExitCode failed: %v", err)
+	}
+
+	if _, err := p.PingExitCode("backups", params, 0, nil); err != nil {
+		t.Fatalf("PingExitCode failed: %v", err)
+	}
+
+	if len(pushes) != 2 {
+		t.Fatalf("expected 2 pushes, got %d", len(pushes))
+	}
+
+	const expPath = "/metrics/job/backups/instance/db1/path@base64/L3Zhci9iYWNrdXBz"
+	for _, p := range pushes {
+		if p.path != expPath {
+			t.Errorf("expected push to %s, got %s", expPath, p.path)
+		}
+	}
+
+	for _, line := range []string{"runitor_last_run_exit_code 3\n", "runitor_last_run_duration_seconds 1.5\n", "runitor_runs_total 1\n"} {
+		if !strings.Contains(pushes[0].body, line) {
+			t.Errorf("expected first push to contain %q, got:\n%s", line, pushes[0].body)
+		}
+	}
+
+	if strings.Contains(pushes[0].body, "runitor_last_success_timestamp_seconds") {
+		t.Errorf("expected failed run not to push last success timestamp, got:\n%s", pushes[0].body)
+	}
+
+	for _, line := range []string{"runitor_last_run_exit_code 0\n", "runitor_runs_total 2\n", "runitor_last_success_timestamp_seconds "} {
+		if !strings.Contains(pushes[1].body, line) {
+			t.Errorf("expected second push to contain %q, got:\n%s", line, pushes[1].body)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// uuidVisiblePrefix is the number of leading characters of a check UUID left
+// visible in a redacted handle. It's enough to tell checks apart, but not
+// enough to ping them.
+const uuidVisiblePrefix = 8
+
+// RedactHandle returns a version of a check handle safe to display.
+//
+// The ping key of a "ping-key/slug" handle is replaced with asterisks. A UUID
+// handle keeps its first 8 characters and the rest of the hex digits are
+// replaced with asterisks. Other handles, like check names of backends other
+// than Healthchecks, are returned as is.
+func RedactHandle(handle string) string {
+	if _, slug, ok := strings.Cut(handle, "/"); ok {
+		return "***/" + slug
+	}
+
+	if _, err := ParseUUID(handle); err != nil {
+		return handle
+	}
+
+	b := []byte(handle)
+	for i := uuidVisiblePrefix; i < len(b); i++ {
+		if b[i] != '-' {
+			b[i] = '*'
+		}
+	}
+
+	return string(b)
+}
+
+// RedactURL returns a version of rawURL safe to display. Path segments that
+// are UUIDs are redacted like check UUID handles, and path segments and query
+// values equal to one of secrets, like a ping key, are replaced with
+// asterisks. So is the password in the user info.
+func RedactURL(rawURL string, secrets []string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "[unparsable URL]"
+	}
+
+	isSecret := func(s string) bool {
+		return len(s) > 0 && slices.Contains(secrets, s)
+	}
+
+	segs := strings.Split(u.Path, "/")
+	for i, seg := range segs {
+		if isSecret(seg) {
+			segs[i] = "***"
+		} else {
+			segs[i] = RedactHandle(seg)
+		}
+	}
+	u.Path = strings.Join(segs, "/")
+	// Keep the redaction marks readable instead of percent-encoded.
+	u.RawPath = u.Path
+
+	q, redacted := u.Query(), false
+	for _, vs := range q {
+		for i, v := range vs {
+			if isSecret(v) {
+				vs[i], redacted = "***", true
+			}
+		}
+	}
+	if redacted {
+		u.RawQuery = q.Encode()
+	}
+
+	return u.Redacted()
+}
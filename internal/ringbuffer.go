@@ -3,6 +3,7 @@
 package internal
 
 import (
+	"bytes"
 	"errors"
 	"io"
 )
@@ -74,6 +75,19 @@ func (r *RingBuffer) Wrapped() bool {
 	return r.Len() == r.Cap() && r.idx > 0
 }
 
+// Snapshot returns a copy of the contents of the ring buffer, oldest byte
+// first, without making it read only. It's meaningful only before the first
+// read.
+func (r *RingBuffer) Snapshot() []byte {
+	if r.Len() < r.Cap() {
+		return bytes.Clone(r.buf)
+	}
+
+	b := make([]byte, 0, r.Len())
+	b = append(b, r.buf[r.idx:]...)
+	return append(b, r.buf[:r.idx]...)
+}
+
 func (r *RingBuffer) Write(p []byte) (n int, err error) {
 	if r.writeClosed {
 		return 0, ErrReadOnly
@@ -57,6 +57,22 @@ func TestSeek(t *testing.T) {
 	}
 }
 
+func TestSnapshot(t *testing.T) {
+	for name, tc := range ReadbackTests {
+		rb := NewRingBuffer(RCap)
+		fmt.Fprint(rb, tc.str)
+
+		if out := string(rb.Snapshot()); out != tc.out {
+			t.Errorf("%s: expected snapshot '%s', got '%s'", name, tc.out, out)
+		}
+
+		// Snapshot doesn't make it read only.
+		if _, err := rb.Write([]byte("x")); err != nil {
+			t.Errorf("%s: write after snapshot failed: %v", name, err)
+		}
+	}
+}
+
 func TestNoWriteAfterRead(t *testing.T) {
 	rb := NewRingBuffer(RCap)
 	rb.Write([]byte{1})
@@ -0,0 +1,24 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+// RunAs holds the credentials and identity the command gets executed with.
+type RunAs struct {
+	Uid, Gid uint32
+	Groups   []uint32 // Supplementary groups
+	Username string   // Empty if only the group was changed
+	HomeDir  string   // Empty if only the group was changed
+}
+
+// Env returns the environment variables identifying the user to the command.
+func (ra *RunAs) Env() []string {
+	if len(ra.Username) == 0 {
+		return nil
+	}
+
+	return []string{
+		"HOME=" + ra.HomeDir,
+		"USER=" + ra.Username,
+		"LOGNAME=" + ra.Username,
+	}
+}
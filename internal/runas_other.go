@@ -0,0 +1,22 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+
+//go:build !unix
+
+package internal
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// LookupRunAs always fails on platforms without Unix style process
+// credentials.
+func LookupRunAs(userName, groupName string) (*RunAs, error) {
+	return nil, fmt.Errorf("running the command as a different user or group is not supported on %s", runtime.GOOS)
+}
+
+// SetCredential is a no-op on platforms without Unix style process
+// credentials.
+func (ra *RunAs) SetCredential(c *exec.Cmd) {}
@@ -0,0 +1,105 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+
+//go:build unix
+
+package internal_test
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// unknownId returns a numeric id without an entry in the user or group
+// database, or skips the test.
+func unknownId(t *testing.T, lookup func(string) error) string {
+	t.Helper()
+
+	for id := 54321; id < 54400; id++ {
+		if err := lookup(strconv.Itoa(id)); err != nil {
+			return strconv.Itoa(id)
+		}
+	}
+
+	t.Skip("no unused id found")
+	return ""
+}
+
+func TestLookupRunAsNumeric(t *testing.T) {
+	t.Parallel()
+
+	u, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	ra, err := LookupRunAs(u.Uid, u.Gid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strconv.Itoa(int(ra.Uid)) != u.Uid || strconv.Itoa(int(ra.Gid)) != u.Gid {
+		t.Errorf("expected %s:%s, got %d:%d", u.Uid, u.Gid, ra.Uid, ra.Gid)
+	}
+	if ra.Username != u.Username || ra.HomeDir != u.HomeDir {
+		t.Errorf("expected the user's identity, got %q, %q", ra.Username, ra.HomeDir)
+	}
+}
+
+// Tests if passing only a group keeps the current user, with the group as its
+// only group.
+func TestLookupRunAsGroupOnly(t *testing.T) {
+	t.Parallel()
+
+	gid := unknownId(t, func(id string) error { _, err := user.LookupGroupId(id); return err })
+
+	ra, err := LookupRunAs("", gid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if int(ra.Uid) != os.Getuid() || strconv.Itoa(int(ra.Gid)) != gid || !slices.Equal(ra.Groups, []uint32{ra.Gid}) {
+		t.Errorf("unexpected credentials %+v", ra)
+	}
+	if env := ra.Env(); env != nil {
+		t.Errorf("expected the identity to be left alone, got %q", env)
+	}
+}
+
+// Tests if a user id without an entry is refused without a group, and gets
+// only the group passed, never runitor's own.
+func TestLookupRunAsUnknownUser(t *testing.T) {
+	t.Parallel()
+
+	uid := unknownId(t, func(id string) error { _, err := user.LookupId(id); return err })
+	gid := unknownId(t, func(id string) error { _, err := user.LookupGroupId(id); return err })
+
+	if _, err := LookupRunAs(uid, ""); err == nil {
+		t.Error("expected an unknown user id without a group to be refused")
+	}
+
+	ra, err := LookupRunAs(uid, gid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strconv.Itoa(int(ra.Uid)) != uid || strconv.Itoa(int(ra.Gid)) != gid || !slices.Equal(ra.Groups, []uint32{ra.Gid}) {
+		t.Errorf("unexpected credentials %+v", ra)
+	}
+
+	want := []string{"HOME=/", "USER=" + uid, "LOGNAME=" + uid}
+	if env := ra.Env(); !slices.Equal(env, want) {
+		t.Errorf("expected %q, got %q", want, env)
+	}
+
+	var unknownUser user.UnknownUserError
+	if _, err := LookupRunAs("no-such-user-runitor", ""); !errors.As(err, &unknownUser) {
+		t.Errorf("expected an unknown user name to be refused, got %v", err)
+	}
+}
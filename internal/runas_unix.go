@@ -0,0 +1,134 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+
+//go:build unix
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// LookupRunAs resolves user and group names or numeric ids to the credentials
+// to execute the command with.
+//
+// If only userName is passed, the command runs with the user's primary group
+// and the supplementary groups the user is a member of. If only groupName is
+// passed, the command runs as the current user with the group as its primary
+// and only group.
+//
+// Numeric ids without an entry in the user or group database are used as is.
+// A user id without an entry requires groupName, so the command never
+// inherits runitor's own groups, and gets / as HOME and the id as USER.
+func LookupRunAs(userName, groupName string) (*RunAs, error) {
+	ra := &RunAs{
+		Uid: uint32(os.Getuid()),
+		Gid: uint32(os.Getgid()),
+	}
+
+	if len(userName) > 0 {
+		u, err := lookupUser(userName)
+		var unknownId user.UnknownUserIdError
+		if errors.As(err, &unknownId) {
+			if len(groupName) == 0 {
+				return nil, fmt.Errorf("user %s has no entry in the user database. Pass the group to run with", userName)
+			}
+
+			ra.Uid = uint32(unknownId)
+			ra.Username, ra.HomeDir = userName, "/"
+			goto Group
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if ra.Uid, err = parseId(u.Uid); err != nil {
+			return nil, fmt.Errorf("user %s: %w", userName, err)
+		}
+
+		if ra.Gid, err = parseId(u.Gid); err != nil {
+			return nil, fmt.Errorf("user %s: %w", userName, err)
+		}
+
+		gids, err := u.GroupIds()
+		if err != nil {
+			return nil, fmt.Errorf("user %s: supplementary groups: %w", userName, err)
+		}
+
+		for _, gid := range gids {
+			id, err := parseId(gid)
+			if err != nil {
+				return nil, fmt.Errorf("user %s: %w", userName, err)
+			}
+			ra.Groups = append(ra.Groups, id)
+		}
+
+		ra.Username, ra.HomeDir = u.Username, u.HomeDir
+	}
+
+Group:
+	if len(groupName) > 0 {
+		g, err := lookupGroup(groupName)
+		var unknownId user.UnknownGroupIdError
+		switch {
+		case errors.As(err, &unknownId):
+			ra.Gid, _ = parseId(string(unknownId))
+		case err != nil:
+			return nil, err
+		default:
+			if ra.Gid, err = parseId(g.Gid); err != nil {
+				return nil, fmt.Errorf("group %s: %w", groupName, err)
+			}
+		}
+
+		if len(ra.Groups) == 0 {
+			ra.Groups = []uint32{ra.Gid}
+		}
+	}
+
+	return ra, nil
+}
+
+// SetCredential configures c to start the process with the credentials in ra.
+func (ra *RunAs) SetCredential(c *exec.Cmd) {
+	if c.SysProcAttr == nil {
+		c.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	c.SysProcAttr.Credential = &syscall.Credential{
+		Uid:    ra.Uid,
+		Gid:    ra.Gid,
+		Groups: ra.Groups,
+	}
+}
+
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return user.LookupId(name)
+	}
+
+	return user.Lookup(name)
+}
+
+func lookupGroup(name string) (*user.Group, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return user.LookupGroupId(name)
+	}
+
+	return user.LookupGroup(name)
+}
+
+func parseId(id string) (uint32, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", id)
+	}
+
+	return uint32(n), nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// RunResult is the outcome of a finished run as reported in metrics and
+// over the control socket.
+type RunResult struct {
+	ExitCode      int       `json:"exit_code"`
+	Success       bool      `json:"success"`        // The command exited with zero
+	Started       time.Time `json:"started"`        // When the command was started
+	Ended         time.Time `json:"ended"`          // When the command exited
+	OutputBytes   int64     `json:"output_bytes"`   // Size of the captured output of the command
+	PingDelivered bool      `json:"ping_delivered"` // The final ping of the run was delivered
+	Skipped       bool      `json:"skipped"`        // The run was skipped without executing the command
+	Output        []byte    `json:"-"`              // Output sent in the ping body, if kept with RunConfig.KeepOutput
+}
+
+// Duration returns how long the command ran.
+func (r *RunResult) Duration() time.Duration {
+	return r.Ended.Sub(r.Started)
+}
+
+// RunStats accumulates the results of the runs in this process for metrics.
+// The zero value is ready to use.
+type RunStats struct {
+	mu          sync.Mutex
+	runs        uint64
+	last        RunResult
+	lastSuccess time.Time
+}
+
+// Record adds the result r of a finished run to the stats. Skipped runs
+// aren't recorded.
+func (s *RunStats) Record(r RunResult) {
+	if r.Skipped {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs++
+	s.last = r
+	if r.Success {
+		s.lastSuccess = r.Ended
+	}
+}
+
+// Metrics returns the metrics describing the recorded runs, with labels added
+// to every sample. No metrics are returned before a run is recorded.
+//
+// The last success timestamp is only included after a successful run. Details
+// of the last run, like its start and end time, output size, and ping
+// delivery, are only included if detailed is true.
+func (s *RunStats) Metrics(labels []PromLabel, detailed bool) []PromMetric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.runs == 0 {
+		return nil
+	}
+
+	gauge := func(name, help string, v float64) PromMetric {
+		return PromMetric{Name: name, Help: help, Type: "gauge", Samples: []PromSample{{Labels: labels, Value: v}}}
+	}
+
+	r := s.last
+	metrics := []PromMetric{
+		gauge("runitor_last_run_exit_code", "Exit code of the last run of the command.", float64(r.ExitCode)),
+		gauge("runitor_last_run_duration_seconds", "Duration of the last run of the command in seconds.", r.Duration().Seconds()),
+		{
+			Name:    "runitor_runs_total",
+			Help:    "Number of runs of the command.",
+			Type:    "counter",
+			Samples: []PromSample{{Labels: labels, Value: float64(s.runs)}},
+		},
+	}
+
+	if !s.lastSuccess.IsZero() {
+		metrics = append(metrics, gauge("runitor_last_success_timestamp_seconds", "Unix timestamp of the last successful run of the command.", unixSeconds(s.lastSuccess)))
+	}
+
+	if detailed {
+		delivered := 0.0
+		if r.PingDelivered {
+			delivered = 1
+		}
+
+		metrics = append(metrics,
+			gauge("runitor_last_run_start_timestamp_seconds", "Unix timestamp of the start of the last run of the command.", unixSeconds(r.Started)),
+			gauge("runitor_last_run_end_timestamp_seconds", "Unix timestamp of the end of the last run of the command.", unixSeconds(r.Ended)),
+			gauge("runitor_last_run_output_bytes", "Size of the captured output of the last run of the command.", float64(r.OutputBytes)),
+			gauge("runitor_last_run_ping_delivered", "Whether the final ping of the last run was delivered (1) or not (0).", delivered),
+		)
+	}
+
+	return metrics
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrInvalidDSN is returned by ParseSentryDSN for malformed DSNs.
+var ErrInvalidDSN = errors.New("invalid Sentry DSN")
+
+// SentryDSN is a parsed Sentry Data Source Name in
+// "{scheme}://{public_key}@{host}{/path}/{project_id}" form.
+type SentryDSN struct {
+	Scheme    string
+	Host      string
+	Path      string // path prefix before the project id, if any
+	PublicKey string
+	ProjectID string
+}
+
+// ParseSentryDSN parses dsn. A secret key in the DSN is accepted but ignored.
+func ParseSentryDSN(dsn string) (*SentryDSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidDSN, u.Scheme)
+	}
+
+	if u.User == nil || len(u.User.Username()) == 0 {
+		return nil, fmt.Errorf("%w: missing public key", ErrInvalidDSN)
+	}
+
+	prefix, projectID := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if len(projectID) == 0 || len(u.Host) == 0 {
+		return nil, fmt.Errorf("%w: missing host or project id", ErrInvalidDSN)
+	}
+
+	return &SentryDSN{
+		Scheme:    u.Scheme,
+		Host:      u.Host,
+		Path:      strings.TrimSuffix(prefix, "/"),
+		PublicKey: u.User.Username(),
+		ProjectID: projectID,
+	}, nil
+}
+
+// CronURL returns the check-in endpoint of the monitor with slug.
+func (d *SentryDSN) CronURL(slug string) (string, error) {
+	p, err := url.JoinPath(d.Path, "api", d.ProjectID, "cron", slug, d.PublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	// The endpoint requires the trailing slash.
+	u := url.URL{Scheme: d.Scheme, Host: d.Host, Path: p + "/"}
+
+	return u.String(), nil
+}
+
+// sentryCheckIn is the check-in payload.
+//
+// https://develop.sentry.dev/sdk/check-ins/
+type sentryCheckIn struct {
+	CheckInID string   `json:"check_in_id,omitempty"`
+	Status    string   `json:"status"`
+	Duration  *float64 `json:"duration,omitempty"` // seconds
+}
+
+// SentryPinger implements Pinger for Sentry Cron Monitors. The check handle
+// is the monitor slug and the run id is used as the check-in id, so the final
+// check-in of a run updates the one created by its start.
+//
+// Start pings are sent as in_progress check-ins, success pings and zero exit
+// codes as ok, and failures and nonzero exit codes as error. Sentry has no log
+// check-ins, so these pings aren't sent.
+type SentryPinger struct {
+	DSN *SentryDSN
+
+	// Client sends the check-ins.
+	Client *APIClient
+}
+
+// PingStart sends an in_progress check-in for the monitor slug handle.
+func (s *SentryPinger) PingStart(handle string, params PingParams) (*InstanceConfig, error) {
+	return s.checkIn(handle, params, "in_progress")
+}
+
+// PingLog is a no-op. Sentry has no log check-ins.
+func (s *SentryPinger) PingLog(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return &InstanceConfig{}, nil
+}
+
+// PingSuccess sends an ok check-in for the monitor slug handle.
+func (s *SentryPinger) PingSuccess(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return s.checkIn(handle, params, "ok")
+}
+
+// PingFail sends an error check-in for the monitor slug handle.
+func (s *SentryPinger) PingFail(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return s.checkIn(handle, params, "error")
+}
+
+// PingExitCode sends an ok check-in for a zero exitCode and an error check-in
+// otherwise for the monitor slug handle.
+func (s *SentryPinger) PingExitCode(handle string, params PingParams, exitCode int, body io.ReadSeeker) (*InstanceConfig, error) {
+	if exitCode != 0 {
+		return s.checkIn(handle, params, "error")
+	}
+
+	return s.checkIn(handle, params, "ok")
+}
+
+func (s *SentryPinger) checkIn(handle string, params PingParams, status string) (*InstanceConfig, error) {
+	u, err := s.DSN.CronURL(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	ci := sentryCheckIn{CheckInID: params.RunId, Status: status}
+	if status != "in_progress" && params.Duration > 0 {
+		d := params.Duration.Seconds()
+		ci.Duration = &d
+	}
+
+	b, err := json.Marshal(ci)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	resp, err := s.Client.RequestAny2xx("POST", u, header, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	return &InstanceConfig{}, nil
+}
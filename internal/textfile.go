@@ -0,0 +1,55 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+// WriteTextfile writes metrics in Prometheus text exposition format to path
+// for node_exporter's textfile collector. The file is replaced atomically, so
+// the collector never reads a partially written file.
+func WriteTextfile(path string, metrics []PromMetric) error {
+	var b bytes.Buffer
+	if err := WritePromText(&b, metrics); err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(path, b.Bytes(), 0o644)
+}
+
+// WriteFileAtomic writes data to a temporary file in the directory of path
+// and renames it to path. The temporary file is hidden and doesn't have the
+// extension of path, so it's ignored by readers looking for it.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(path)
+	if len(dir) == 0 {
+		dir = "."
+	}
+
+	f, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op after a successful rename
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
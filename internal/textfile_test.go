@@ -0,0 +1,85 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if WriteTextfile replaces the file with detailed run metrics and
+// leaves no temporary files behind.
+func TestWriteTextfile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "backups.prom")
+
+	if err := os.WriteFile(path, []byte("stale\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stats RunStats
+	started := time.Unix(1700000000, 0)
+	stats.Record(RunResult{
+		ExitCode:      2,
+		Started:       started,
+		Ended:         started.Add(2500 * time.Millisecond),
+		OutputBytes:   42,
+		PingDelivered: true,
+	})
+
+	labels := []PromLabel{{"check", "***/backups"}, {"job", "backup"}}
+	if err := WriteTextfile(path, stats.Metrics(labels, true)); err != nil {
+		t.Fatalf("WriteTextfile failed: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		`runitor_last_run_exit_code{check="***/backups",job="backup"} 2`,
+		`runitor_last_run_duration_seconds{check="***/backups",job="backup"} 2.5`,
+		`runitor_last_run_start_timestamp_seconds{check="***/backups",job="backup"} 1700000000`,
+		`runitor_last_run_end_timestamp_seconds{check="***/backups",job="backup"} 1700000002.5`,
+		`runitor_last_run_output_bytes{check="***/backups",job="backup"} 42`,
+		`runitor_last_run_ping_delivered{check="***/backups",job="backup"} 1`,
+		`runitor_runs_total{check="***/backups",job="backup"} 1`,
+	} {
+		if !strings.Contains(string(b), line+"\n") {
+			t.Errorf("expected textfile to contain %q, got:\n%s", line, b)
+		}
+	}
+
+	if strings.Contains(string(b), "stale") || strings.Contains(string(b), "runitor_last_success_timestamp_seconds") {
+		t.Errorf("unexpected textfile content:\n%s", b)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("expected only the textfile in directory, got %v", entries)
+	}
+}
+
+// Tests if skipped runs aren't recorded.
+func TestRunStatsSkipped(t *testing.T) {
+	t.Parallel()
+
+	var stats RunStats
+	stats.Record(RunResult{Skipped: true})
+
+	if m := stats.Metrics(nil, true); m != nil {
+		t.Errorf("expected no metrics for skipped runs, got %+v", m)
+	}
+}
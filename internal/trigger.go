@@ -0,0 +1,118 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// triggerOutputLimit is the size of the output tail returned to run requests
+// waiting for the result.
+const triggerOutputLimit = 10_000
+
+// ErrRunPending is returned by TriggerHandler.Trigger if a requested run
+// hasn't started yet.
+var ErrRunPending = errors.New("a run is already pending")
+
+// TriggerResponse is the reply to a run request on the trigger endpoint.
+type TriggerResponse struct {
+	Error  string     `json:"error,omitempty"`
+	RunId  string     `json:"run_id,omitempty"`
+	Result *RunResult `json:"result,omitempty"` // Only if waited for
+	Output string     `json:"output,omitempty"` // Tail of the output sent in the ping body, if waited for
+}
+
+// TriggerHandler is an http.Handler starting a run on POST /run requests
+// authenticated with a bearer token.
+//
+// The run id is returned right away, unless the request has the wait query
+// parameter set. Then the reply is sent after the run with its result and
+// the tail of its output.
+type TriggerHandler struct {
+	Token string // Bearer token requests must be authenticated with
+
+	// Trigger requests a run and returns its run id, and if wait, a channel
+	// receiving its result with the output. The channel is closed without a
+	// result if the run is canceled before it starts.
+	Trigger func(wait bool) (runId string, result <-chan RunResult, err error)
+}
+
+// authorized reports whether r carries the token in its Authorization header.
+func (h *TriggerHandler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || len(h.Token) == 0 {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) == 1
+}
+
+func (h *TriggerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(status int, resp *TriggerResponse) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	}
+
+	if r.URL.Path != "/run" {
+		reply(http.StatusNotFound, &TriggerResponse{Error: "not found"})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		reply(http.StatusMethodNotAllowed, &TriggerResponse{Error: "method not allowed"})
+		return
+	}
+
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		reply(http.StatusUnauthorized, &TriggerResponse{Error: "unauthorized"})
+		return
+	}
+
+	var wait bool
+	if v := r.URL.Query().Get("wait"); len(v) > 0 {
+		var err error
+		if wait, err = strconv.ParseBool(v); err != nil {
+			reply(http.StatusBadRequest, &TriggerResponse{Error: "invalid wait parameter"})
+			return
+		}
+	}
+
+	runId, result, err := h.Trigger(wait)
+	switch {
+	case errors.Is(err, ErrRunPending):
+		reply(http.StatusConflict, &TriggerResponse{Error: err.Error()})
+		return
+	case err != nil:
+		reply(http.StatusServiceUnavailable, &TriggerResponse{Error: err.Error()})
+		return
+	}
+
+	if !wait {
+		reply(http.StatusAccepted, &TriggerResponse{RunId: runId})
+		return
+	}
+
+	select {
+	case res, ok := <-result:
+		if !ok {
+			reply(http.StatusServiceUnavailable, &TriggerResponse{RunId: runId, Error: "run canceled"})
+			return
+		}
+
+		reply(http.StatusOK, &TriggerResponse{
+			RunId:  runId,
+			Result: &res,
+			Output: outputTail(string(res.Output), triggerOutputLimit),
+		})
+	case <-r.Context().Done():
+		// The client is gone. The run goes on.
+	}
+}
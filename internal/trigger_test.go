@@ -0,0 +1,104 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if TriggerHandler rejects unauthenticated and malformed requests,
+// replies with the run id right away, and with the result and the output when
+// asked to wait, or an error if the run is canceled.
+func TestTriggerHandler(t *testing.T) {
+	t.Parallel()
+
+	pending, canceled := false, false
+	h := &TriggerHandler{
+		Token: "s3cret",
+		Trigger: func(wait bool) (string, <-chan RunResult, error) {
+			if pending {
+				return "", nil, ErrRunPending
+			}
+			if canceled {
+				done := make(chan RunResult)
+				close(done)
+				return "run-3", done, nil
+			}
+			if !wait {
+				return "run-1", nil, nil
+			}
+
+			done := make(chan RunResult, 1)
+			done <- RunResult{ExitCode: 2, Output: []byte("backup failed\n")}
+			return "run-2", done, nil
+		},
+	}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	post := func(path, auth string) (int, TriggerResponse) {
+		t.Helper()
+
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, nil)
+		if len(auth) > 0 {
+			req.Header.Set("Authorization", auth)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var tr TriggerResponse
+		if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+			t.Fatalf("decoding reply to %s: %v", path, err)
+		}
+
+		return resp.StatusCode, tr
+	}
+
+	for _, tc := range []struct {
+		path, auth string
+		status     int
+	}{
+		{"/run", "", http.StatusUnauthorized},
+		{"/run", "Bearer wrong", http.StatusUnauthorized},
+		{"/run", "Basic s3cret", http.StatusUnauthorized},
+		{"/run?wait=maybe", "Bearer s3cret", http.StatusBadRequest},
+		{"/other", "Bearer s3cret", http.StatusNotFound},
+	} {
+		if status, _ := post(tc.path, tc.auth); status != tc.status {
+			t.Errorf("POST %s with %q: expected status %d, got %d", tc.path, tc.auth, tc.status, status)
+		}
+	}
+
+	if resp, err := http.Get(srv.URL + "/run"); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected GET to be rejected, got %v, %v", resp.Status, err)
+	}
+
+	if status, tr := post("/run", "Bearer s3cret"); status != http.StatusAccepted || tr.RunId != "run-1" || tr.Result != nil {
+		t.Errorf("unexpected reply %d %+v", status, tr)
+	}
+
+	status, tr := post("/run?wait=1", "Bearer s3cret")
+	if status != http.StatusOK || tr.RunId != "run-2" || tr.Result == nil || tr.Result.ExitCode != 2 || tr.Output != "backup failed" {
+		t.Errorf("unexpected reply %d %+v", status, tr)
+	}
+
+	canceled = true
+	if status, tr := post("/run?wait=1", "Bearer s3cret"); status != http.StatusServiceUnavailable || tr.RunId != "run-3" || tr.Result != nil {
+		t.Errorf("unexpected reply %d %+v", status, tr)
+	}
+
+	pending = true
+	if status, tr := post("/run", "Bearer s3cret"); status != http.StatusConflict || tr.Error != ErrRunPending.Error() {
+		t.Errorf("unexpected reply %d %+v", status, tr)
+	}
+}
@@ -4,11 +4,21 @@ package internal
 
 import (
 	"crypto/rand"
+	"crypto/sha1"
 	"encoding/hex"
+	"errors"
 )
 
 const uuid4RandBytes = 128 / 8
 
+// UUIDNamespaceURL is the namespace for URLs from RFC 9562 to be used with
+// NewUUID5.
+const UUIDNamespaceURL = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+
+// ErrInvalidUUID is returned by ParseUUID for strings not in the canonical
+// 8-4-4-4-12 hex digits form.
+var ErrInvalidUUID = errors.New("invalid UUID")
+
 func NewUUID4() (string, error) {
 	rnd := make([]byte, uuid4RandBytes)
 	_, err := rand.Read(rnd)
@@ -19,19 +29,67 @@ func NewUUID4() (string, error) {
 	rnd[6] = (rnd[6] & 0x0f) | 0x40 // version 4
 	rnd[8] = (rnd[8] & 0x3f) | 0x80 // variant 0b10
 
+	return formatUUID(rnd), nil
+}
+
+// NewUUID5 returns the name based UUID (version 5) of name in namespace ns, as
+// specified in RFC 9562. Same namespace and name always produce the same UUID.
+func NewUUID5(ns, name string) (string, error) {
+	nsb, err := parseUUID(ns)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha1.New()
+	h.Write(nsb)
+	h.Write([]byte(name))
+	sum := h.Sum(nil)[:uuid4RandBytes]
+
+	sum[6] = (sum[6] & 0x0f) | 0x50 // version 5
+	sum[8] = (sum[8] & 0x3f) | 0x80 // variant 0b10
+
+	return formatUUID(sum), nil
+}
+
+// ParseUUID validates s as a UUID in the canonical form and returns it with
+// hex digits in lower case.
+func ParseUUID(s string) (string, error) {
+	b, err := parseUUID(s)
+	if err != nil {
+		return "", err
+	}
+
+	return formatUUID(b), nil
+}
+
+func parseUUID(s string) ([]byte, error) {
+	if len(s) != 2*uuid4RandBytes+4 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return nil, ErrInvalidUUID
+	}
+
+	digits := s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, ErrInvalidUUID
+	}
+
+	return b, nil
+}
+
+func formatUUID(b []byte) string {
 	var str [2*uuid4RandBytes + 4]byte
 	//       \______________/ \_/
 	//              |          |_> 4 '-' separators
 	//              |____________> 2 hex digits per byte
-	hex.Encode(str[:], rnd[:4])
+	hex.Encode(str[:], b[:4])
 	str[8] = '-'
-	hex.Encode(str[9:13], rnd[4:6])
+	hex.Encode(str[9:13], b[4:6])
 	str[13] = '-'
-	hex.Encode(str[14:18], rnd[6:8])
+	hex.Encode(str[14:18], b[6:8])
 	str[18] = '-'
-	hex.Encode(str[19:23], rnd[8:10])
+	hex.Encode(str[19:23], b[8:10])
 	str[23] = '-'
-	hex.Encode(str[24:], rnd[10:])
+	hex.Encode(str[24:], b[10:])
 
-	return string(str[:]), nil
+	return string(str[:])
 }
@@ -0,0 +1,80 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"testing"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+const NamespaceDNS = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+func TestNewUUID4(t *testing.T) {
+	t.Parallel()
+
+	u, err := NewUUID4()
+	if err != nil {
+		t.Fatalf("NewUUID4 failed: %v", err)
+	}
+
+	if _, err := ParseUUID(u); err != nil {
+		t.Errorf("NewUUID4 returned unparsable UUID %s", u)
+	}
+
+	if u[14] != '4' {
+		t.Errorf("expected version 4 UUID, got %s", u)
+	}
+}
+
+func TestNewUUID5(t *testing.T) {
+	t.Parallel()
+
+	// Test vector from Python's uuid.uuid5(uuid.NAMESPACE_DNS, 'python.org')
+	const expected = "886313e1-3b8a-5372-9b90-0c9aee199e5d"
+
+	u, err := NewUUID5(NamespaceDNS, "python.org")
+	if err != nil {
+		t.Fatalf("NewUUID5 failed: %v", err)
+	}
+
+	if u != expected {
+		t.Errorf("expected %s, got %s", expected, u)
+	}
+
+	if _, err := NewUUID5("not-a-uuid", "python.org"); err == nil {
+		t.Errorf("expected NewUUID5 to fail with an invalid namespace")
+	}
+}
+
+func TestParseUUID(t *testing.T) {
+	t.Parallel()
+
+	valid := map[string]string{
+		TestRunId:                              TestRunId,
+		"6BA7B810-9DAD-11D1-80B4-00C04FD430C8": NamespaceDNS,
+	}
+
+	for in, expected := range valid {
+		got, err := ParseUUID(in)
+		if err != nil {
+			t.Errorf("ParseUUID(%q) failed: %v", in, err)
+		} else if got != expected {
+			t.Errorf("ParseUUID(%q): expected %s, got %s", in, expected, got)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"6ba7b8109dad11d180b400c04fd430c8",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430cg",
+		"6ba7b810+9dad-11d1-80b4-00c04fd430c8",
+	}
+
+	for _, in := range invalid {
+		if _, err := ParseUUID(in); err == nil {
+			t.Errorf("expected ParseUUID(%q) to fail", in)
+		}
+	}
+}
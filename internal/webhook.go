@@ -0,0 +1,145 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// DefaultWebhookTemplate is the payload template used when none is supplied.
+const DefaultWebhookTemplate = `{
+  "event": {{json .Event}},
+  "check": {{json .Check}},
+  "run_id": {{json .RunId}},
+  "exit_code": {{json .ExitCode}},
+  "duration": {{json .Duration.Seconds}},
+  "hostname": {{json .Hostname}},
+  "time": {{json .Time}},
+  "output": {{json .Output}}
+}
+`
+
+// WebhookEvent is the data passed to the payload template of WebhookPinger.
+type WebhookEvent struct {
+	Event    string        // start, success, fail or log
+	Check    string        // check handle
+	RunId    string        // empty if run ids are disabled
+	ExitCode *int          // nil if the command hasn't finished
+	Duration time.Duration // zero if the command hasn't finished
+	Hostname string
+	Time     time.Time
+	Output   string // captured output of the command
+}
+
+// NewWebhookTemplate parses text as a payload template for WebhookPinger. In
+// addition to text/template's builtins, templates can use the json function
+// to encode values as JSON.
+func NewWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+}
+
+// WebhookPinger implements Pinger by posting a JSON document rendered from a
+// template to an arbitrary URL, for alerting endpoints other than
+// Healthchecks.
+//
+// Exit code pings are sent as success events for a zero exit code and fail
+// events otherwise.
+type WebhookPinger struct {
+	// URL is the address payloads are posted to.
+	URL string
+
+	// Template renders WebhookEvents to payloads. Rendered payloads must
+	// be valid JSON.
+	Template *template.Template
+
+	// Hostname is reported in the events.
+	Hostname string
+
+	// Client posts the payloads.
+	Client *APIClient
+}
+
+// PingStart posts a start event for the check handle.
+func (w *WebhookPinger) PingStart(handle string, params PingParams) (*InstanceConfig, error) {
+	return w.post("start", handle, params, params.ExitCode, nil)
+}
+
+// PingSuccess posts a success event for the check handle with body as the
+// output.
+func (w *WebhookPinger) PingSuccess(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return w.post("success", handle, params, params.ExitCode, body)
+}
+
+// PingFail posts a fail event for the check handle with body as the output.
+func (w *WebhookPinger) PingFail(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return w.post("fail", handle, params, params.ExitCode, body)
+}
+
+// PingLog posts a log event for the check handle with body as the output.
+func (w *WebhookPinger) PingLog(handle string, params PingParams, body io.ReadSeeker) (*InstanceConfig, error) {
+	return w.post("log", handle, params, params.ExitCode, body)
+}
+
+// PingExitCode posts a success or fail event depending on exitCode for the
+// check handle with body as the output.
+func (w *WebhookPinger) PingExitCode(handle string, params PingParams, exitCode int, body io.ReadSeeker) (*InstanceConfig, error) {
+	event := "success"
+	if exitCode != 0 {
+		event = "fail"
+	}
+
+	return w.post(event, handle, params, Some(exitCode), body)
+}
+
+func (w *WebhookPinger) post(event, handle string, params PingParams, exitCode Optional[int], body io.ReadSeeker) (*InstanceConfig, error) {
+	ev := WebhookEvent{
+		Event:    event,
+		Check:    handle,
+		RunId:    params.RunId,
+		Duration: params.Duration,
+		Hostname: w.Hostname,
+		Time:     time.Now().UTC(),
+	}
+
+	if code, ok := exitCode.Get(); ok {
+		ev.ExitCode = &code
+	}
+
+	if body != nil {
+		out, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		ev.Output = string(out)
+	}
+
+	var payload bytes.Buffer
+	if err := w.Template.Execute(&payload, ev); err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(payload.Bytes()) {
+		return nil, fmt.Errorf("webhook template rendered invalid JSON: %q", payload.String())
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	resp, err := w.Client.RequestAny2xx("POST", w.URL, header, bytes.NewReader(payload.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	return &InstanceConfig{}, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Berk D. Demir and the runitor contributors.
+// SPDX-License-Identifier: 0BSD
+package internal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "bdd.fi/x/runitor/internal"
+)
+
+// Tests if WebhookPinger posts the default payload with the event details.
+func TestWebhookPinger(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]any
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected content-type application/json, got %s", ct)
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("cannot decode payload: %v", err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	tmpl, err := NewWebhookTemplate(DefaultWebhookTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &WebhookPinger{URL: ts.URL, Template: tmpl, Hostname: "host", Client: &APIClient{Client: ts.Client()}}
+	params := PingParams{RunId: TestRunId, Duration: 1500 * time.Millisecond}
+
+	if _, err := w.PingExitCode("backups", params, 3, bytes.NewReader(TestPingBody)); err != nil {
+		t.Fatalf("PingExitCode failed: %v", err)
+	}
+
+	exp := map[string]any{
+		"event":     "fail",
+		"check":     "backups",
+		"run_id":    TestRunId,
+		"exit_code": 3.0,
+		"duration":  1.5,
+		"hostname":  "host",
+		"output":    string(TestPingBody),
+	}
+
+	for k, v := range exp {
+		if got[k] != v {
+			t.Errorf("expected %s to be %v, got %v", k, v, got[k])
+		}
+	}
+
+	if _, err := w.PingStart("backups", params); err != nil {
+		t.Fatalf("PingStart failed: %v", err)
+	}
+
+	if got["event"] != "start" || got["exit_code"] != nil {
+		t.Errorf("unexpected start payload: %v", got)
+	}
+}
+
+// Tests if templates rendering invalid JSON are rejected before posting.
+func TestWebhookPingerInvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request to be made")
+	}))
+	defer ts.Close()
+
+	tmpl, err := NewWebhookTemplate(`{"output": {{.Output}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &WebhookPinger{URL: ts.URL, Template: tmpl, Client: &APIClient{Client: ts.Client()}}
+	if _, err := w.PingLog("backups", PingParams{}, bytes.NewReader(TestPingBody)); err == nil {
+		t.Error("expected an error for invalid JSON payload")
+	}
+}